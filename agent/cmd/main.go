@@ -2,60 +2,111 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/aniket/servertui/agent/internal/config"
+	"github.com/aniket/servertui/agent/internal/logging"
 	"github.com/aniket/servertui/agent/internal/server"
+	"github.com/aniket/servertui/agent/internal/version"
 )
 
 func main() {
-	// Configure logging for Docker - immediate output, include timestamps
-	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
-	log.SetOutput(os.Stdout)
-
-	log.Println("========================================")
-	log.Println("ServerTUI Agent Starting...")
-	log.Println("========================================")
-
-	// Parse configuration from command line flags
-	cfg := config.ParseFlags()
-	log.Printf("Config: port=%d, cert=%s, key=%s", cfg.Port, cfg.TLSCertPath, cfg.TLSKeyPath)
+	// Load configuration from the config file and command line flags
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		slog.Error("Invalid configuration", "error", err)
+		os.Exit(1)
+	}
 
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
-		log.Fatalf("Invalid configuration: %v", err)
+		slog.Error("Invalid configuration", "error", err)
+		os.Exit(1)
 	}
 
+	// Configure logging for Docker - immediate output to stdout, include timestamps
+	level, _ := logging.ParseLevel(cfg.LogLevel)
+	logging.Setup(level, cfg.LogFormat)
+
+	slog.Info("========================================")
+	slog.Info("ServerTUI Agent Starting...")
+	slog.Info("========================================")
+	build := version.Get()
+	slog.Info("Version", "version", build.Version, "commit", build.Commit, "buildDate", build.BuildDate, "go", build.GoVersion)
+	slog.Info("Config", "configFile", cfg.ConfigPath, "addr", cfg.ListenAddr(), "socket", cfg.SocketPath, "cert", cfg.TLSCertPath, "key", cfg.TLSKeyPath, "clientCA", cfg.ClientCAPath, "dockerHost", cfg.DockerHost, "logLevel", cfg.LogLevel, "logFormat", cfg.LogFormat)
+
 	// Create and start server
-	log.Println("Creating server instance...")
-	srv := server.New(cfg)
+	slog.Debug("Creating server instance")
+	srv, err := server.New(cfg)
+	if err != nil {
+		slog.Error("Failed to create server", "error", err)
+		os.Exit(1)
+	}
 
 	// Handle graceful shutdown
+	shutdownDone := make(chan struct{})
 	go func() {
+		defer close(shutdownDone)
+
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 		<-sigChan
 
-		log.Println("Shutting down server...")
+		slog.Info("Shutting down server...")
 
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
 		if err := srv.Shutdown(ctx); err != nil {
-			log.Printf("Error during shutdown: %v", err)
+			slog.Error("Error during shutdown", "error", err)
 		}
+	}()
 
-		os.Exit(0)
+	// Reload configuration and TLS certificates on SIGHUP
+	go func() {
+		hupChan := make(chan os.Signal, 1)
+		signal.Notify(hupChan, syscall.SIGHUP)
+		for range hupChan {
+			slog.Info("Received SIGHUP, reloading configuration")
+			reloadConfig(srv)
+			srv.ReloadCertificates()
+		}
 	}()
 
 	// Start the server
-	log.Printf("Server agent starting on port %d with TLS", cfg.Port)
-	log.Println("Waiting for connections...")
-	if err := srv.Start(); err != nil {
-		log.Fatalf("Server error: %v", err)
+	slog.Info("Server agent starting", "addr", cfg.ListenAddr())
+	slog.Info("Waiting for connections...")
+	if err := srv.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("Server error", "error", err)
+		os.Exit(1)
+	}
+
+	// Start returns as soon as shutdown begins; wait for draining to finish
+	<-shutdownDone
+	slog.Info("Server stopped")
+}
+
+// reloadConfig re-reads the configuration and applies it to srv.
+// An invalid configuration is logged and the running settings are kept.
+func reloadConfig(srv *server.Server) {
+	cfg, err := config.Load(os.Args[1:])
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err == nil {
+		err = srv.Reload(cfg)
+	}
+	if err != nil {
+		slog.Error("Failed to reload configuration, keeping current settings", "error", err)
 	}
 }
@@ -0,0 +1,330 @@
+// Package alerts evaluates threshold rules against sampled metrics.
+package alerts
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aniket/servertui/agent/internal/metrics"
+)
+
+// Metrics that rules can refer to.
+const (
+	MetricCPU    = "cpu"
+	MetricMemory = "memory"
+	MetricDisk   = "disk"
+)
+
+// Rule is a threshold condition that must hold for a duration before firing,
+// written as "<metric> <op> <threshold>[%] [for <duration>]", e.g. "cpu > 90 for 5m".
+//
+// A disk rule may name a mount point, as in "disk:/var > 80". Mounts with
+// rules of their own are checked only against those; plain "disk" rules are
+// the default for every other mount.
+type Rule struct {
+	Expr      string
+	Metric    string
+	Mount     string // disk rules only; empty for the default
+	Op        string
+	Threshold float64
+	For       time.Duration
+}
+
+// ParseRule parses a rule expression.
+func ParseRule(expr string) (Rule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 3 && len(fields) != 5 {
+		return Rule{}, fmt.Errorf("invalid alert rule %q: expected \"<metric> <op> <threshold> [for <duration>]\"", expr)
+	}
+
+	r := Rule{Expr: strings.Join(fields, " "), Metric: fields[0], Op: fields[1]}
+	if metric, mount, ok := strings.Cut(r.Metric, ":"); ok && metric == MetricDisk {
+		if mount == "" {
+			return Rule{}, fmt.Errorf("invalid alert rule %q: missing mount point after \"disk:\"", expr)
+		}
+		r.Metric, r.Mount = metric, mount
+	}
+
+	switch r.Metric {
+	case MetricCPU, MetricMemory, MetricDisk:
+	default:
+		return Rule{}, fmt.Errorf("invalid alert rule %q: unknown metric %q", expr, r.Metric)
+	}
+
+	switch r.Op {
+	case ">", ">=", "<", "<=":
+	default:
+		return Rule{}, fmt.Errorf("invalid alert rule %q: unknown operator %q", expr, r.Op)
+	}
+
+	threshold, err := strconv.ParseFloat(strings.TrimSuffix(fields[2], "%"), 64)
+	if err != nil {
+		return Rule{}, fmt.Errorf("invalid alert rule %q: bad threshold %q", expr, fields[2])
+	}
+	r.Threshold = threshold
+
+	if len(fields) == 5 {
+		d, err := time.ParseDuration(fields[4])
+		if fields[3] != "for" || err != nil || d < 0 {
+			return Rule{}, fmt.Errorf("invalid alert rule %q: expected \"for <duration>\"", expr)
+		}
+		r.For = d
+	}
+
+	return r, nil
+}
+
+// matches reports whether value meets the rule's condition.
+func (r Rule) matches(value float64) bool {
+	switch r.Op {
+	case ">":
+		return value > r.Threshold
+	case ">=":
+		return value >= r.Threshold
+	case "<":
+		return value < r.Threshold
+	case "<=":
+		return value <= r.Threshold
+	}
+	return false
+}
+
+// values extracts the rule's metric from m, keyed by mount point for disk
+// rules and by "" otherwise. Mounts in explicit are skipped by default disk
+// rules. It returns nothing if the metric's category was not collected.
+func (r Rule) values(m *metrics.Metrics, explicit map[string]bool) map[string]float64 {
+	switch r.Metric {
+	case MetricCPU:
+		if m.CPU != nil {
+			return map[string]float64{"": m.CPU.UsagePercent}
+		}
+	case MetricMemory:
+		if m.Memory != nil {
+			return map[string]float64{"": m.Memory.UsagePercent}
+		}
+	case MetricDisk:
+		if m.Disk == nil {
+			return nil
+		}
+		usage := map[string]float64{m.Disk.MountPoint: m.Disk.UsagePercent}
+		for _, p := range m.Disk.Partitions {
+			usage[p.MountPoint] = p.UsagePercent
+		}
+		if r.Mount != "" {
+			if value, ok := usage[r.Mount]; ok {
+				return map[string]float64{r.Mount: value}
+			}
+			return nil
+		}
+		for mount := range explicit {
+			delete(usage, mount)
+		}
+		return usage
+	}
+	return nil
+}
+
+// sampled reports whether m includes the rule's metric category.
+func (r Rule) sampled(m *metrics.Metrics) bool {
+	switch r.Metric {
+	case MetricCPU:
+		return m.CPU != nil
+	case MetricMemory:
+		return m.Memory != nil
+	case MetricDisk:
+		return m.Disk != nil
+	}
+	return false
+}
+
+// Alert states.
+const (
+	StatePending  = "pending"  // condition holds but not yet for the rule's duration
+	StateFiring   = "firing"   // condition has held for the rule's duration
+	StateResolved = "resolved" // a firing alert's condition no longer holds
+)
+
+// Alert is the current state of a rule whose condition holds.
+type Alert struct {
+	Rule      string  `json:"rule"`
+	Metric    string  `json:"metric"`
+	Mount     string  `json:"mount,omitempty"` // the disk the alert is about
+	Threshold float64 `json:"threshold"`
+	Value     float64 `json:"value"`
+	State     string  `json:"state"`
+	Since     int64   `json:"since"` // unix milliseconds the condition started to hold
+}
+
+// Notification reports an alert firing or resolving.
+type Notification struct {
+	Alert
+	Time int64 `json:"time"` // unix milliseconds of the transition
+}
+
+// stateKey identifies what a ruleState tracks: one rule, and for disk rules
+// one mount point.
+type stateKey struct {
+	rule  int
+	mount string
+}
+
+// ruleState tracks a rule between evaluations.
+type ruleState struct {
+	since  time.Time // zero while the condition doesn't hold
+	firing bool
+	value  float64
+}
+
+// Engine evaluates rules against metrics samples and tracks which are firing.
+// It is safe for concurrent use.
+type Engine struct {
+	mu     sync.Mutex
+	rules  []Rule
+	states map[stateKey]*ruleState
+	subs   map[chan Notification]struct{}
+
+	// mounts holds the mount points that have disk rules of their own
+	mounts map[string]bool
+}
+
+// NewEngine creates an engine for rules.
+func NewEngine(rules []Rule) *Engine {
+	e := &Engine{
+		rules:  rules,
+		states: make(map[stateKey]*ruleState),
+		subs:   make(map[chan Notification]struct{}),
+		mounts: make(map[string]bool),
+	}
+	for _, rule := range rules {
+		if rule.Mount != "" {
+			e.mounts[rule.Mount] = true
+		}
+	}
+	return e
+}
+
+// Rules returns the engine's rules.
+func (e *Engine) Rules() []Rule {
+	return e.rules
+}
+
+// Evaluate updates every rule with the sample m taken at now and notifies
+// subscribers of alerts that fire or resolve. An alert for a mount that is
+// no longer in the sample, such as an unmounted disk, resolves.
+func (e *Engine) Evaluate(m *metrics.Metrics, now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	seen := make(map[stateKey]bool, len(e.states))
+	for i, rule := range e.rules {
+		for mount, value := range rule.values(m, e.mounts) {
+			key := stateKey{rule: i, mount: mount}
+			seen[key] = true
+			st := e.states[key]
+			if st == nil {
+				st = &ruleState{}
+				e.states[key] = st
+			}
+
+			st.value = value
+			if !rule.matches(value) {
+				if st.firing {
+					e.notify(rule, mount, st, StateResolved, now)
+				}
+				*st = ruleState{value: value}
+				continue
+			}
+
+			if st.since.IsZero() {
+				st.since = now
+			}
+			if !st.firing && now.Sub(st.since) >= rule.For {
+				st.firing = true
+				e.notify(rule, mount, st, StateFiring, now)
+			}
+		}
+	}
+
+	// A category that wasn't collected this time says nothing about its mounts
+	for key, st := range e.states {
+		rule := e.rules[key.rule]
+		if seen[key] || !rule.sampled(m) {
+			continue
+		}
+		if st.firing {
+			e.notify(rule, key.mount, st, StateResolved, now)
+		}
+		delete(e.states, key)
+	}
+}
+
+// Alerts returns the pending and firing alerts.
+func (e *Engine) Alerts() []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	keys := make([]stateKey, 0, len(e.states))
+	for key, st := range e.states {
+		if !st.since.IsZero() {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, func(a, b stateKey) int {
+		return cmp.Or(cmp.Compare(a.rule, b.rule), cmp.Compare(a.mount, b.mount))
+	})
+
+	alerts := []Alert{}
+	for _, key := range keys {
+		st := e.states[key]
+		state := StatePending
+		if st.firing {
+			state = StateFiring
+		}
+		alerts = append(alerts, newAlert(e.rules[key.rule], key.mount, *st, state))
+	}
+	return alerts
+}
+
+// Subscribe returns a channel of alert notifications and a function that
+// cancels the subscription. Notifications are dropped if the channel is full.
+func (e *Engine) Subscribe(buffer int) (<-chan Notification, func()) {
+	ch := make(chan Notification, buffer)
+
+	e.mu.Lock()
+	e.subs[ch] = struct{}{}
+	e.mu.Unlock()
+
+	return ch, func() {
+		e.mu.Lock()
+		delete(e.subs, ch)
+		e.mu.Unlock()
+	}
+}
+
+// notify sends a notification to every subscriber. Called with e.mu held.
+func (e *Engine) notify(rule Rule, mount string, st *ruleState, state string, now time.Time) {
+	n := Notification{Alert: newAlert(rule, mount, *st, state), Time: now.UnixMilli()}
+	for ch := range e.subs {
+		select {
+		case ch <- n:
+		default:
+		}
+	}
+}
+
+func newAlert(rule Rule, mount string, st ruleState, state string) Alert {
+	return Alert{
+		Rule:      rule.Expr,
+		Metric:    rule.Metric,
+		Mount:     mount,
+		Threshold: rule.Threshold,
+		Value:     st.value,
+		State:     state,
+		Since:     st.since.UnixMilli(),
+	}
+}
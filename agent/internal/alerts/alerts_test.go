@@ -0,0 +1,54 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aniket/servertui/agent/internal/metrics"
+)
+
+// diskSample returns metrics with the root filesystem and the given
+// partitions, as mount point to usage percent.
+func diskSample(partitions map[string]float64) *metrics.Metrics {
+	m := &metrics.Metrics{Disk: &metrics.DiskMetrics{MountPoint: "/", UsagePercent: 10}}
+	for mount, usage := range partitions {
+		m.Disk.Partitions = append(m.Disk.Partitions, metrics.PartitionMetrics{MountPoint: mount, UsagePercent: usage})
+	}
+	return m
+}
+
+func TestEvaluateResolvesMissingMount(t *testing.T) {
+	rule, err := ParseRule("disk > 80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEngine([]Rule{rule})
+	notifications, cancel := e.Subscribe(10)
+	defer cancel()
+
+	now := time.Now()
+	e.Evaluate(diskSample(map[string]float64{"/mnt/backup": 95}), now)
+	if n := <-notifications; n.State != StateFiring || n.Mount != "/mnt/backup" {
+		t.Fatalf("got %s for %q, want firing for /mnt/backup", n.State, n.Mount)
+	}
+
+	// A sample without the disk category says nothing about the mount
+	e.Evaluate(&metrics.Metrics{}, now.Add(time.Minute))
+	if alerts := e.Alerts(); len(alerts) != 1 {
+		t.Fatalf("got %d alerts after a sample without disks, want 1", len(alerts))
+	}
+
+	// Unmounting the disk resolves its alert
+	e.Evaluate(diskSample(nil), now.Add(2*time.Minute))
+	select {
+	case n := <-notifications:
+		if n.State != StateResolved || n.Mount != "/mnt/backup" {
+			t.Errorf("got %s for %q, want resolved for /mnt/backup", n.State, n.Mount)
+		}
+	default:
+		t.Fatal("no notification for the unmounted disk")
+	}
+	if alerts := e.Alerts(); len(alerts) != 0 {
+		t.Errorf("got %d alerts after unmounting, want none", len(alerts))
+	}
+}
@@ -0,0 +1,116 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Webhook delivery settings.
+const (
+	webhookTimeout     = 10 * time.Second
+	webhookAttempts    = 4
+	webhookBaseBackoff = 1 * time.Second
+)
+
+// WebhookPayload is the JSON body posted when an alert fires or resolves.
+type WebhookPayload struct {
+	Hostname  string  `json:"hostname"`
+	Status    string  `json:"status"` // firing or resolved
+	Rule      string  `json:"rule"`
+	Metric    string  `json:"metric"`
+	Mount     string  `json:"mount,omitempty"` // the disk the alert is about
+	Threshold float64 `json:"threshold"`
+	Value     float64 `json:"value"`
+	Since     int64   `json:"since"` // unix milliseconds the condition started to hold
+	Time      int64   `json:"time"`  // unix milliseconds of the transition
+}
+
+// Webhook posts alert notifications to a URL.
+type Webhook struct {
+	url      string
+	hostname string
+	client   *http.Client
+}
+
+// NewWebhook creates a webhook that posts to url, identifying the agent as hostname.
+func NewWebhook(url, hostname string) *Webhook {
+	return &Webhook{
+		url:      url,
+		hostname: hostname,
+		client:   &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// Run delivers notifications until ctx is canceled. Delivery failures are
+// logged and the notification is dropped.
+func (w *Webhook) Run(ctx context.Context, notifications <-chan Notification) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-notifications:
+			if err := w.Send(ctx, n); err != nil && ctx.Err() == nil {
+				slog.Error("Failed to deliver alert webhook", "rule", n.Rule, "state", n.State, "error", err)
+			}
+		}
+	}
+}
+
+// Send posts n, retrying with exponential backoff on failure.
+func (w *Webhook) Send(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(WebhookPayload{
+		Hostname:  w.hostname,
+		Status:    n.State,
+		Rule:      n.Rule,
+		Metric:    n.Metric,
+		Mount:     n.Mount,
+		Threshold: n.Threshold,
+		Value:     n.Value,
+		Since:     n.Since,
+		Time:      n.Time,
+	})
+	if err != nil {
+		return err
+	}
+
+	backoff := webhookBaseBackoff
+	for attempt := 1; ; attempt++ {
+		err = w.post(ctx, body)
+		if err == nil || attempt == webhookAttempts {
+			return err
+		}
+		slog.Warn("Alert webhook failed, retrying", "attempt", attempt, "backoff", backoff, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post makes a single delivery attempt.
+func (w *Webhook) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
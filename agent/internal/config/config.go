@@ -2,47 +2,558 @@
 package config
 
 import (
+	"bufio"
 	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // Config holds the agent configuration.
 type Config struct {
+	// ConfigPath is an optional file of settings, one "flag-name = value" per line
+	ConfigPath string
+
 	// Port is the port to listen on (default 8443)
 	Port int
 
+	// BindAddress is the IP address to listen on (empty means all interfaces)
+	BindAddress string
+
+	// SocketPath makes the agent listen on a Unix domain socket instead of TCP
+	SocketPath string
+
+	// InstanceName identifies this agent in responses and alerts (empty uses the OS hostname)
+	InstanceName string
+
 	// TLSCertPath is the path to the TLS certificate file
 	TLSCertPath string
 
 	// TLSKeyPath is the path to the TLS private key file
 	TLSKeyPath string
 
+	// ClientCAPath is a CA bundle used to verify client certificates (enables mTLS)
+	ClientCAPath string
+
+	// HTTPReadTimeout bounds reading a whole request, including the body (0 disables)
+	HTTPReadTimeout time.Duration
+
+	// HTTPWriteTimeout bounds writing a response, measured from the end of the
+	// request headers (0 disables). Long exec and update calls need it raised.
+	HTTPWriteTimeout time.Duration
+
+	// HTTPIdleTimeout is how long an idle keep-alive connection stays open (0 disables)
+	HTTPIdleTimeout time.Duration
+
 	// MetricsInterval is how often to stream metrics via WebSocket
 	MetricsInterval time.Duration
+
+	// MetricsJitter varies each metrics sampling interval randomly by up to
+	// this many percent either way, so agents started together drift apart (0 disables)
+	MetricsJitter int
+
+	// DockerHost is the Docker daemon address (empty uses DOCKER_HOST or the local socket)
+	DockerHost string
+
+	// DockerTLSCAPath, DockerTLSCertPath and DockerTLSKeyPath configure TLS to a remote daemon
+	DockerTLSCAPath   string
+	DockerTLSCertPath string
+	DockerTLSKeyPath  string
+
+	// DockerTimeout bounds each Docker API call other than log, event and build streams (0 disables)
+	DockerTimeout time.Duration
+
+	// WSCompression negotiates permessage-deflate on WebSocket connections
+	WSCompression bool
+
+	// WSReadBufferSize and WSWriteBufferSize size each WebSocket connection's I/O buffers in bytes
+	WSReadBufferSize  int
+	WSWriteBufferSize int
+
+	// WSWriteTimeout bounds every WebSocket write; a client that stalls past it is
+	// disconnected (0 disables)
+	WSWriteTimeout time.Duration
+
+	// HistoryRetention is how long sampled metrics are kept in memory (0 disables history)
+	HistoryRetention time.Duration
+
+	// HistoryInterval is how often metrics are sampled into history
+	HistoryInterval time.Duration
+
+	// DisabledMetrics is a comma-separated list of metric categories to skip
+	DisabledMetrics string
+
+	// GPUMetrics enables NVIDIA GPU metrics collection when nvidia-smi is present
+	GPUMetrics bool
+
+	// DiskInclude, DiskExclude and DiskExcludeFSTypes are comma-separated lists
+	// selecting the partitions reported with disk metrics. Mount patterns are
+	// globs that also match everything mounted beneath them; an empty include
+	// list reports every mount.
+	DiskInclude        string
+	DiskExclude        string
+	DiskExcludeFSTypes string
+
+	// CommandTimeout bounds how long exec and package commands may run (0 disables)
+	CommandTimeout time.Duration
+
+	// UpdatesCacheTTL is how long package update results are reused (0 disables caching)
+	UpdatesCacheTTL time.Duration
+
+	// UpdatesRefreshInterval is how often the package index is refreshed in the background (0 disables)
+	UpdatesRefreshInterval time.Duration
+
+	// ExecDisabled rejects every /api/exec request when set
+	ExecDisabled bool
+
+	// ExecAllowlistPath is a file of permitted commands for /api/exec (empty allows all)
+	ExecAllowlistPath string
+
+	// HostControl enables the reboot and shutdown endpoints
+	HostControl bool
+
+	// HostShutdownDelay is how long after a request a reboot or shutdown happens
+	HostShutdownDelay time.Duration
+
+	// ExecDirs is a comma-separated list of directories exec commands may run in (empty allows any)
+	ExecDirs string
+
+	// AlertRules are threshold rules such as "cpu > 90 for 5m"
+	AlertRules []string
+
+	// AlertInterval is how often alert rules are evaluated
+	AlertInterval time.Duration
+
+	// AlertWebhook is a URL that receives a JSON POST when an alert fires or resolves
+	AlertWebhook string
+
+	// DiagDNSHost is the hostname resolved by the connectivity check (empty skips it)
+	DiagDNSHost string
+
+	// DiagTCPTarget is the host:port dialed by the connectivity check (empty skips it)
+	DiagTCPTarget string
+
+	// DiagHTTPURL is fetched by the connectivity check (empty skips it)
+	DiagHTTPURL string
+
+	// DiagTimeout bounds each connectivity check and disk probe
+	DiagTimeout time.Duration
+
+	// DiskProbePaths is a comma-separated list of directories the disk probe
+	// writes to (empty probes every partition reported with disk metrics)
+	DiskProbePaths string
+
+	// DiskProbeSize is how many bytes the disk probe writes and reads back
+	DiskProbeSize int
+
+	// MaxBodyBytes caps the size of request bodies (0 disables the cap)
+	MaxBodyBytes int64
+
+	// MaxLogBytes caps the size of container log downloads (0 disables the cap)
+	MaxLogBytes int64
+
+	// EnablePprof serves net/http/pprof profiles on PprofAddr
+	EnablePprof bool
+
+	// PprofAddr is the host:port the pprof server listens on
+	PprofAddr string
+
+	// LogLevel is the minimum log level (debug, info, warn, error)
+	LogLevel string
+
+	// LogFormat is the log output format (text or json)
+	LogFormat string
+
+	// Prometheus serves host and container metrics at /metrics without authentication
+	Prometheus bool
 }
 
 // DefaultConfig returns the default configuration.
 func DefaultConfig() *Config {
 	return &Config{
-		Port:            8443,
-		TLSCertPath:     "",
-		TLSKeyPath:      "",
-		MetricsInterval: 1 * time.Second,
+		Port:              8443,
+		TLSCertPath:       "",
+		TLSKeyPath:        "",
+		HTTPReadTimeout:   15 * time.Second,
+		HTTPWriteTimeout:  15 * time.Second,
+		HTTPIdleTimeout:   60 * time.Second,
+		MetricsInterval:   1 * time.Second,
+		DockerTimeout:     30 * time.Second,
+		WSCompression:     true,
+		WSReadBufferSize:  1024,
+		WSWriteBufferSize: 1024,
+		WSWriteTimeout:    10 * time.Second,
+		HistoryRetention:  1 * time.Hour,
+		HistoryInterval:   10 * time.Second,
+		GPUMetrics:        true,
+		CommandTimeout:    10 * time.Minute,
+		UpdatesCacheTTL:   10 * time.Minute,
+		HostShutdownDelay: time.Minute,
+		AlertInterval:     15 * time.Second,
+		DiagDNSHost:       "example.com",
+		DiagTCPTarget:     "1.1.1.1:443",
+		DiagTimeout:       5 * time.Second,
+		DiskProbeSize:     4096,
+		MaxBodyBytes:      1 << 20,
+		MaxLogBytes:       10 << 20,
+		PprofAddr:         "127.0.0.1:6060",
+		LogLevel:          "info",
+		LogFormat:         "text",
+		Prometheus:        true,
+
+		// Partition filter
+		DiskExclude:        defaultDiskExclude,
+		DiskExcludeFSTypes: defaultDiskExcludeFSTypes,
 	}
 }
 
-// ParseFlags parses command line flags into a Config.
-func ParseFlags() *Config {
+// Load builds the configuration from defaults, the optional config file and
+// the command line args, in increasing order of precedence.
+func Load(args []string) (*Config, error) {
 	cfg := DefaultConfig()
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	cfg.bindFlags(fs)
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if cfg.ConfigPath == "" {
+		return cfg, nil
+	}
+
+	// Flags given on the command line override the file
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	if err := loadFile(fs, cfg.ConfigPath, explicit); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// bindFlags registers the command line flags on fs.
+func (c *Config) bindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.ConfigPath, "config", c.ConfigPath, "Path to config file of flag-name = value lines")
+	fs.IntVar(&c.Port, "port", c.Port, "Port to listen on")
+	fs.StringVar(&c.BindAddress, "bind", c.BindAddress, "IP address to listen on, e.g. 127.0.0.1 or [::1] (default all interfaces)")
+	fs.StringVar(&c.SocketPath, "socket", c.SocketPath, "Listen on this Unix domain socket instead of TCP")
+	fs.StringVar(&c.InstanceName, "instance-name", c.InstanceName, "Name identifying this agent in responses and alerts (default the OS hostname)")
+	fs.StringVar(&c.TLSCertPath, "tls-cert", c.TLSCertPath, "Path to TLS certificate file")
+	fs.StringVar(&c.TLSKeyPath, "tls-key", c.TLSKeyPath, "Path to TLS private key file")
+	fs.StringVar(&c.ClientCAPath, "client-ca", c.ClientCAPath, "Path to CA bundle for verifying client certificates (requires them on every connection)")
+	fs.DurationVar(&c.HTTPReadTimeout, "http-read-timeout", c.HTTPReadTimeout, "Maximum time to read a request (0 disables)")
+	fs.DurationVar(&c.HTTPWriteTimeout, "http-write-timeout", c.HTTPWriteTimeout, "Maximum time to write a response (0 disables)")
+	fs.DurationVar(&c.HTTPIdleTimeout, "http-idle-timeout", c.HTTPIdleTimeout, "How long idle keep-alive connections stay open (0 disables)")
+	fs.DurationVar(&c.MetricsInterval, "metrics-interval", c.MetricsInterval, "Metrics streaming interval")
+	fs.IntVar(&c.MetricsJitter, "metrics-jitter", c.MetricsJitter, "Randomly vary metrics sampling intervals by up to this percentage either way (0 disables)")
+	fs.StringVar(&c.DockerHost, "docker-host", c.DockerHost, "Docker daemon address, e.g. unix:///var/run/docker.sock or tcp://host:2376")
+	fs.StringVar(&c.DockerTLSCAPath, "docker-tls-ca", c.DockerTLSCAPath, "CA certificate for verifying a remote Docker daemon")
+	fs.StringVar(&c.DockerTLSCertPath, "docker-tls-cert", c.DockerTLSCertPath, "Client certificate for a remote Docker daemon")
+	fs.StringVar(&c.DockerTLSKeyPath, "docker-tls-key", c.DockerTLSKeyPath, "Client key for a remote Docker daemon")
+	fs.DurationVar(&c.DockerTimeout, "docker-timeout", c.DockerTimeout, "Maximum time for a Docker API call; keep above the 10s container stop grace period (0 disables)")
+	fs.BoolVar(&c.WSCompression, "ws-compression", c.WSCompression, "Compress WebSocket messages with permessage-deflate when the client supports it")
+	fs.IntVar(&c.WSReadBufferSize, "ws-read-buffer", c.WSReadBufferSize, "WebSocket read buffer size in bytes")
+	fs.IntVar(&c.WSWriteBufferSize, "ws-write-buffer", c.WSWriteBufferSize, "WebSocket write buffer size in bytes")
+	fs.DurationVar(&c.WSWriteTimeout, "ws-write-timeout", c.WSWriteTimeout, "Disconnect WebSocket clients that take longer than this to accept a message (0 disables)")
+	fs.DurationVar(&c.HistoryRetention, "history-retention", c.HistoryRetention, "How long to keep metrics history in memory (0 disables)")
+	fs.DurationVar(&c.HistoryInterval, "history-interval", c.HistoryInterval, "Metrics history sampling interval")
+	fs.Var((*stringList)(&c.AlertRules), "alert", "Alert rule such as \"cpu > 90 for 5m\" or \"disk:/var > 80\" (repeatable)")
+	fs.DurationVar(&c.AlertInterval, "alert-interval", c.AlertInterval, "How often alert rules are evaluated")
+	fs.StringVar(&c.AlertWebhook, "alert-webhook", c.AlertWebhook, "URL to POST alert notifications to")
+	fs.StringVar(&c.DiagDNSHost, "diag-dns-host", c.DiagDNSHost, "Hostname resolved by the connectivity check (empty skips DNS)")
+	fs.StringVar(&c.DiagTCPTarget, "diag-tcp-target", c.DiagTCPTarget, "host:port dialed by the connectivity check (empty skips TCP)")
+	fs.StringVar(&c.DiagHTTPURL, "diag-http-url", c.DiagHTTPURL, "URL fetched by the connectivity check (empty skips HTTP)")
+	fs.DurationVar(&c.DiagTimeout, "diag-timeout", c.DiagTimeout, "Timeout for each connectivity check and disk probe")
+	fs.StringVar(&c.DiskProbePaths, "disk-probe-paths", c.DiskProbePaths, "Comma-separated absolute directories the disk probe writes to (default every reported partition)")
+	fs.IntVar(&c.DiskProbeSize, "disk-probe-size", c.DiskProbeSize, "Bytes written and read back by the disk probe")
+	fs.Int64Var(&c.MaxBodyBytes, "max-body-bytes", c.MaxBodyBytes, "Maximum request body size in bytes (0 disables)")
+	fs.Int64Var(&c.MaxLogBytes, "max-log-bytes", c.MaxLogBytes, "Maximum bytes returned by a container log download (0 disables)")
+	fs.StringVar(&c.DisabledMetrics, "disable-metrics", c.DisabledMetrics, "Comma-separated metric categories to skip: cpu, memory, disk, network")
+	fs.BoolVar(&c.GPUMetrics, "gpu-metrics", c.GPUMetrics, "Collect NVIDIA GPU metrics via nvidia-smi when available")
+	fs.BoolVar(&c.Prometheus, "prometheus", c.Prometheus, "Serve Prometheus metrics at /metrics; unlike /api this endpoint needs no authentication")
+	fs.StringVar(&c.DiskInclude, "disk-include", c.DiskInclude, "Comma-separated mount point globs to report partitions for (default all)")
+	fs.StringVar(&c.DiskExclude, "disk-exclude", c.DiskExclude, "Comma-separated mount point globs to leave out of partition metrics; a pattern also covers mounts beneath it")
+	fs.StringVar(&c.DiskExcludeFSTypes, "disk-exclude-fstypes", c.DiskExcludeFSTypes, "Comma-separated filesystem types to leave out of partition metrics")
+	fs.BoolVar(&c.EnablePprof, "enable-pprof", c.EnablePprof, "Serve pprof profiles under /debug/pprof on --pprof-addr")
+	fs.StringVar(&c.PprofAddr, "pprof-addr", c.PprofAddr, "Address of the pprof server; keep it on localhost")
+	fs.StringVar(&c.LogLevel, "log-level", c.LogLevel, "Log level: debug, info, warn or error")
+	fs.StringVar(&c.LogFormat, "log-format", c.LogFormat, "Log format: text or json")
+	fs.BoolVar(&c.ExecDisabled, "exec-disabled", c.ExecDisabled, "Disable arbitrary command execution via /api/exec")
+	fs.StringVar(&c.ExecAllowlistPath, "exec-allowlist", c.ExecAllowlistPath, "Path to file of permitted exec commands or prefixes")
+	fs.BoolVar(&c.HostControl, "host-control", c.HostControl, "Allow rebooting and shutting down the host via /api/host")
+	fs.DurationVar(&c.HostShutdownDelay, "host-shutdown-delay", c.HostShutdownDelay, "Delay before a requested reboot or shutdown, rounded up to whole minutes")
+	fs.StringVar(&c.ExecDirs, "exec-dirs", c.ExecDirs, "Comma-separated absolute directories exec requests may set as their working directory (default any)")
+	fs.DurationVar(&c.CommandTimeout, "command-timeout", c.CommandTimeout, "Maximum run time for exec and update commands (0 disables)")
+	fs.DurationVar(&c.UpdatesCacheTTL, "updates-cache-ttl", c.UpdatesCacheTTL, "How long to reuse package update check results (0 disables)")
+	fs.DurationVar(&c.UpdatesRefreshInterval, "updates-refresh-interval", c.UpdatesRefreshInterval, "Refresh the package index in the background at this interval (0 disables)")
+}
+
+// secretSettings may carry credentials (webhook URLs embed tokens), so
+// Settings hides their values.
+var secretSettings = map[string]bool{"alert-webhook": true}
+
+// urlSettings are URLs that may embed a password.
+var urlSettings = map[string]bool{"docker-host": true, "diag-http-url": true}
+
+// redacted replaces hidden setting values.
+const redacted = "[redacted]"
+
+// Settings returns every setting by flag name, formatted as it would be
+// given on the command line. Secrets set to a value are reported as
+// "[redacted]" and passwords are removed from URLs.
+func (c *Config) Settings() map[string]string {
+	fs := flag.NewFlagSet("settings", flag.ContinueOnError)
+	c.bindFlags(fs)
+
+	settings := make(map[string]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		value := f.Value.String()
+		switch {
+		case value == "":
+		case secretSettings[f.Name]:
+			value = redacted
+		case urlSettings[f.Name]:
+			if u, err := url.Parse(value); err == nil {
+				value = u.Redacted()
+			} else {
+				value = redacted
+			}
+		}
+		settings[f.Name] = value
+	})
+	return settings
+}
+
+// stringList is a flag.Value that collects every value of a repeated flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, "; ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// loadFile applies the settings in path to fs, skipping flags named in explicit.
+// Each non-blank line that doesn't start with '#' is "flag-name = value".
+func loadFile(fs *flag.FlagSet, path string, explicit map[string]bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" {
+			return fmt.Errorf("%s:%d: expected name = value", path, lineNum)
+		}
+		if name == "config" || fs.Lookup(name) == nil {
+			return fmt.Errorf("%s:%d: unknown setting %q", path, lineNum, name)
+		}
+		if explicit[name] {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("%s:%d: invalid value for %s: %w", path, lineNum, name, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	return nil
+}
+
+// RestartRequired returns the flag names of settings that differ between c
+// and next but are only applied at startup.
+func (c *Config) RestartRequired(next *Config) []string {
+	var changed []string
+	check := func(name string, differs bool) {
+		if differs {
+			changed = append(changed, name)
+		}
+	}
+	check("port", c.Port != next.Port)
+	check("bind", c.BindAddress != next.BindAddress)
+	check("socket", c.SocketPath != next.SocketPath)
+	check("instance-name", c.InstanceName != next.InstanceName)
+	check("tls-cert", c.TLSCertPath != next.TLSCertPath)
+	check("tls-key", c.TLSKeyPath != next.TLSKeyPath)
+	check("client-ca", c.ClientCAPath != next.ClientCAPath)
+	check("http-read-timeout", c.HTTPReadTimeout != next.HTTPReadTimeout)
+	check("http-write-timeout", c.HTTPWriteTimeout != next.HTTPWriteTimeout)
+	check("http-idle-timeout", c.HTTPIdleTimeout != next.HTTPIdleTimeout)
+	check("metrics-jitter", c.MetricsJitter != next.MetricsJitter)
+	check("alert", !slices.Equal(c.AlertRules, next.AlertRules))
+	check("alert-interval", c.AlertInterval != next.AlertInterval)
+	check("alert-webhook", c.AlertWebhook != next.AlertWebhook)
+	check("diag-dns-host", c.DiagDNSHost != next.DiagDNSHost)
+	check("diag-tcp-target", c.DiagTCPTarget != next.DiagTCPTarget)
+	check("enable-pprof", c.EnablePprof != next.EnablePprof)
+	check("pprof-addr", c.PprofAddr != next.PprofAddr)
+	check("diag-http-url", c.DiagHTTPURL != next.DiagHTTPURL)
+	check("diag-timeout", c.DiagTimeout != next.DiagTimeout)
+	check("disk-probe-paths", c.DiskProbePaths != next.DiskProbePaths)
+	check("disk-probe-size", c.DiskProbeSize != next.DiskProbeSize)
+	check("max-body-bytes", c.MaxBodyBytes != next.MaxBodyBytes)
+	check("max-log-bytes", c.MaxLogBytes != next.MaxLogBytes)
+	check("docker-host", c.DockerHost != next.DockerHost)
+	check("docker-tls-ca", c.DockerTLSCAPath != next.DockerTLSCAPath)
+	check("docker-tls-cert", c.DockerTLSCertPath != next.DockerTLSCertPath)
+	check("docker-tls-key", c.DockerTLSKeyPath != next.DockerTLSKeyPath)
+	check("docker-timeout", c.DockerTimeout != next.DockerTimeout)
+	check("ws-compression", c.WSCompression != next.WSCompression)
+	check("ws-read-buffer", c.WSReadBufferSize != next.WSReadBufferSize)
+	check("ws-write-buffer", c.WSWriteBufferSize != next.WSWriteBufferSize)
+	check("ws-write-timeout", c.WSWriteTimeout != next.WSWriteTimeout)
+	check("history-retention", c.HistoryRetention != next.HistoryRetention)
+	check("history-interval", c.HistoryInterval != next.HistoryInterval)
+	check("disable-metrics", c.DisabledMetrics != next.DisabledMetrics)
+	check("gpu-metrics", c.GPUMetrics != next.GPUMetrics)
+	check("prometheus", c.Prometheus != next.Prometheus)
+	check("disk-include", c.DiskInclude != next.DiskInclude)
+	check("disk-exclude", c.DiskExclude != next.DiskExclude)
+	check("disk-exclude-fstypes", c.DiskExcludeFSTypes != next.DiskExcludeFSTypes)
+	check("log-format", c.LogFormat != next.LogFormat)
+	check("command-timeout", c.CommandTimeout != next.CommandTimeout)
+	check("updates-cache-ttl", c.UpdatesCacheTTL != next.UpdatesCacheTTL)
+	check("host-control", c.HostControl != next.HostControl)
+	check("host-shutdown-delay", c.HostShutdownDelay != next.HostShutdownDelay)
+	check("updates-refresh-interval", c.UpdatesRefreshInterval != next.UpdatesRefreshInterval)
+	return changed
+}
+
+// defaultDiskExclude skips mounts managed by snap and container runtimes.
+const defaultDiskExclude = "/snap,/var/snap,/var/lib/docker,/var/lib/containers,/var/lib/kubelet"
+
+// defaultDiskExcludeFSTypes skips pseudo, in-memory and read-only image filesystems.
+const defaultDiskExcludeFSTypes = "proc,sysfs,devtmpfs,devpts,tmpfs,ramfs,cgroup,cgroup2,securityfs,debugfs,tracefs," +
+	"pstore,bpf,mqueue,hugetlbfs,configfs,fusectl,autofs,binfmt_misc,nsfs,rpc_pipefs,efivarfs," +
+	"squashfs,overlay,fuse.lxcfs,fuse.portal,fuse.gvfsd-fuse"
+
+// metricsCategories are the valid names for DisabledMetrics.
+var metricsCategories = []string{"cpu", "memory", "disk", "network"}
+
+// DisabledMetricsList returns the disabled metric categories.
+func (c *Config) DisabledMetricsList() []string {
+	return splitList(c.DisabledMetrics)
+}
+
+// ExecDirsList returns the directories exec commands may run in.
+func (c *Config) ExecDirsList() []string {
+	return splitList(c.ExecDirs)
+}
+
+// DiskIncludeList returns the mount patterns to report.
+func (c *Config) DiskIncludeList() []string {
+	return splitList(c.DiskInclude)
+}
+
+// DiskExcludeList returns the mount patterns to skip.
+func (c *Config) DiskExcludeList() []string {
+	return splitList(c.DiskExclude)
+}
+
+// DiskExcludeFSTypesList returns the filesystem types to skip.
+func (c *Config) DiskExcludeFSTypesList() []string {
+	return splitList(c.DiskExcludeFSTypes)
+}
 
-	flag.IntVar(&cfg.Port, "port", cfg.Port, "Port to listen on")
-	flag.StringVar(&cfg.TLSCertPath, "tls-cert", cfg.TLSCertPath, "Path to TLS certificate file")
-	flag.StringVar(&cfg.TLSKeyPath, "tls-key", cfg.TLSKeyPath, "Path to TLS private key file")
-	flag.DurationVar(&cfg.MetricsInterval, "metrics-interval", cfg.MetricsInterval, "Metrics streaming interval")
+// DiskProbePathsList returns the directories the disk probe writes to.
+func (c *Config) DiskProbePathsList() []string {
+	return splitList(c.DiskProbePaths)
+}
+
+// maxDiskProbeSize caps DiskProbeSize so a probe stays a quick health check.
+const maxDiskProbeSize = 16 << 20
+
+// splitList splits a comma-separated list, dropping empty entries.
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// validDockerHost reports whether host is a Docker daemon URL the client can dial.
+func validDockerHost(host string) bool {
+	u, err := url.Parse(host)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "unix", "npipe":
+		return u.Path != ""
+	case "tcp", "http", "https":
+		return u.Host != ""
+	}
+	return false
+}
 
-	flag.Parse()
+// validHTTPURL reports whether raw is an absolute http or https URL.
+func validHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Instance returns InstanceName, falling back to the OS hostname.
+func (c *Config) Instance() string {
+	if c.InstanceName != "" {
+		return c.InstanceName
+	}
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// MinMetricsInterval is the shortest allowed MetricsInterval.
+const MinMetricsInterval = 100 * time.Millisecond
 
-	return cfg
+// maxMetricsJitter caps MetricsJitter so an interval never shrinks below half.
+const maxMetricsJitter = 50
+
+// maxInstanceNameLen bounds InstanceName, which is sent in a response header.
+const maxInstanceNameLen = 253
+
+// validInstanceName accepts short names of printable ASCII, so the name is
+// safe to send as a header value.
+func validInstanceName(name string) bool {
+	if len(name) > maxInstanceNameLen {
+		return false
+	}
+	for _, r := range name {
+		if r < 0x20 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// BindHost returns the bind address without IPv6 brackets.
+func (c *Config) BindHost() string {
+	return strings.TrimSuffix(strings.TrimPrefix(c.BindAddress, "["), "]")
+}
+
+// ListenAddr returns the host:port address to listen on.
+func (c *Config) ListenAddr() string {
+	return net.JoinHostPort(c.BindHost(), strconv.Itoa(c.Port))
 }
 
 // Validate checks if the configuration is valid.
@@ -56,5 +567,111 @@ func (c *Config) Validate() error {
 	if c.Port <= 0 || c.Port > 65535 {
 		return ErrInvalidPort
 	}
+	if c.BindAddress != "" && net.ParseIP(c.BindHost()) == nil {
+		return ErrInvalidBindAddress
+	}
+	if !validInstanceName(c.InstanceName) {
+		return ErrInvalidInstanceName
+	}
+	if c.MetricsInterval < MinMetricsInterval {
+		return ErrInvalidMetricsInterval
+	}
+	if c.MetricsJitter < 0 || c.MetricsJitter > maxMetricsJitter {
+		return ErrInvalidMetricsJitter
+	}
+	if c.HTTPReadTimeout < 0 || c.HTTPWriteTimeout < 0 || c.HTTPIdleTimeout < 0 {
+		return ErrInvalidHTTPTimeout
+	}
+	if c.WSReadBufferSize <= 0 || c.WSWriteBufferSize <= 0 {
+		return ErrInvalidWSBufferSize
+	}
+	if c.WSWriteTimeout < 0 {
+		return ErrInvalidWSWriteTimeout
+	}
+	if c.DockerHost != "" && !validDockerHost(c.DockerHost) {
+		return ErrInvalidDockerHost
+	}
+	if (c.DockerTLSCertPath == "") != (c.DockerTLSKeyPath == "") {
+		return ErrInvalidDockerTLS
+	}
+	if c.DockerTimeout < 0 {
+		return ErrInvalidDockerTimeout
+	}
+	if c.CommandTimeout < 0 {
+		return ErrInvalidCommandTimeout
+	}
+	if c.HostShutdownDelay < 0 {
+		return ErrInvalidHostShutdownDelay
+	}
+	if c.UpdatesCacheTTL < 0 {
+		return ErrInvalidUpdatesCacheTTL
+	}
+	if c.UpdatesRefreshInterval < 0 {
+		return ErrInvalidUpdatesRefreshInterval
+	}
+	if c.HistoryRetention < 0 {
+		return ErrInvalidHistoryRetention
+	}
+	if c.HistoryRetention > 0 && c.HistoryInterval <= 0 {
+		return ErrInvalidHistoryInterval
+	}
+	for _, dir := range c.ExecDirsList() {
+		if !filepath.IsAbs(dir) {
+			return ErrInvalidExecDirs
+		}
+	}
+	for _, name := range c.DisabledMetricsList() {
+		if !slices.Contains(metricsCategories, name) {
+			return ErrInvalidMetricsCategory
+		}
+	}
+	for _, pattern := range append(c.DiskIncludeList(), c.DiskExcludeList()...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return ErrInvalidDiskPattern
+		}
+	}
+	if len(c.AlertRules) > 0 && c.AlertInterval <= 0 {
+		return ErrInvalidAlertInterval
+	}
+	if c.AlertWebhook != "" && !validHTTPURL(c.AlertWebhook) {
+		return ErrInvalidAlertWebhook
+	}
+	if c.EnablePprof {
+		if _, _, err := net.SplitHostPort(c.PprofAddr); err != nil {
+			return ErrInvalidPprofAddr
+		}
+	}
+	if c.DiagTCPTarget != "" {
+		if _, _, err := net.SplitHostPort(c.DiagTCPTarget); err != nil {
+			return ErrInvalidDiagTCPTarget
+		}
+	}
+	if c.DiagHTTPURL != "" && !validHTTPURL(c.DiagHTTPURL) {
+		return ErrInvalidDiagHTTPURL
+	}
+	if c.DiagTimeout <= 0 {
+		return ErrInvalidDiagTimeout
+	}
+	for _, dir := range c.DiskProbePathsList() {
+		if !filepath.IsAbs(dir) {
+			return ErrInvalidDiskProbePaths
+		}
+	}
+	if c.DiskProbeSize <= 0 || c.DiskProbeSize > maxDiskProbeSize {
+		return ErrInvalidDiskProbeSize
+	}
+	if c.MaxBodyBytes < 0 {
+		return ErrInvalidMaxBodyBytes
+	}
+	if c.MaxLogBytes < 0 {
+		return ErrInvalidMaxLogBytes
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return ErrInvalidLogLevel
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		return ErrInvalidLogFormat
+	}
 	return nil
 }
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidateMetricsInterval(t *testing.T) {
+	for _, tc := range []struct {
+		interval time.Duration
+		want     error
+	}{
+		{-time.Second, ErrInvalidMetricsInterval},
+		{0, ErrInvalidMetricsInterval},
+		{MinMetricsInterval - 1, ErrInvalidMetricsInterval},
+		{MinMetricsInterval, nil},
+		{time.Second, nil},
+	} {
+		cfg := DefaultConfig()
+		cfg.TLSCertPath, cfg.TLSKeyPath = "cert.pem", "key.pem"
+		cfg.MetricsInterval = tc.interval
+		if err := cfg.Validate(); !errors.Is(err, tc.want) {
+			t.Errorf("Validate() with interval %v = %v, want %v", tc.interval, err, tc.want)
+		}
+	}
+}
@@ -11,4 +11,97 @@ var (
 
 	// ErrInvalidPort is returned when the port number is invalid.
 	ErrInvalidPort = errors.New("port must be between 1 and 65535")
+
+	// ErrInvalidBindAddress is returned when the bind address is not an IP address.
+	ErrInvalidBindAddress = errors.New("bind address must be an IPv4 or IPv6 address")
+
+	// ErrInvalidInstanceName is returned when the instance name is too long or not printable ASCII.
+	ErrInvalidInstanceName = errors.New("instance name must be at most 253 printable ASCII characters")
+
+	// ErrInvalidMetricsInterval is returned when the metrics interval is below MinMetricsInterval.
+	ErrInvalidMetricsInterval = errors.New("metrics interval must be at least 100ms")
+
+	// ErrInvalidMetricsJitter is returned when the metrics jitter is outside 0-50 percent.
+	ErrInvalidMetricsJitter = errors.New("metrics jitter must be between 0 and 50 percent")
+
+	// ErrInvalidHTTPTimeout is returned when an HTTP server timeout is negative.
+	ErrInvalidHTTPTimeout = errors.New("HTTP timeouts must not be negative")
+
+	// ErrInvalidWSBufferSize is returned when a WebSocket buffer size is not positive.
+	ErrInvalidWSBufferSize = errors.New("WebSocket buffer sizes must be positive")
+
+	// ErrInvalidWSWriteTimeout is returned when the WebSocket write timeout is negative.
+	ErrInvalidWSWriteTimeout = errors.New("WebSocket write timeout must not be negative")
+
+	// ErrInvalidDockerHost is returned when the Docker host is not a supported URL.
+	ErrInvalidDockerHost = errors.New("docker host must be a unix://, npipe://, tcp:// or https:// URL")
+
+	// ErrInvalidDockerTLS is returned when only one of the Docker TLS cert and key is set.
+	ErrInvalidDockerTLS = errors.New("docker TLS certificate and key must be set together")
+
+	// ErrInvalidDockerTimeout is returned when the Docker operation timeout is negative.
+	ErrInvalidDockerTimeout = errors.New("docker timeout must not be negative")
+
+	// ErrInvalidCommandTimeout is returned when the command timeout is negative.
+	ErrInvalidCommandTimeout = errors.New("command timeout must not be negative")
+
+	// ErrInvalidExecDirs is returned when an exec directory is not an absolute path.
+	ErrInvalidExecDirs = errors.New("exec directories must be absolute paths")
+
+	// ErrInvalidPprofAddr is returned when pprof is enabled without a valid host:port.
+	ErrInvalidPprofAddr = errors.New("pprof address must be host:port")
+
+	// ErrInvalidHostShutdownDelay is returned when the host shutdown delay is negative.
+	ErrInvalidHostShutdownDelay = errors.New("host shutdown delay must not be negative")
+
+	// ErrInvalidUpdatesCacheTTL is returned when the updates cache TTL is negative.
+	ErrInvalidUpdatesCacheTTL = errors.New("updates cache TTL must not be negative")
+
+	// ErrInvalidUpdatesRefreshInterval is returned when the updates refresh interval is negative.
+	ErrInvalidUpdatesRefreshInterval = errors.New("updates refresh interval must not be negative")
+
+	// ErrInvalidHistoryRetention is returned when the history retention is negative.
+	ErrInvalidHistoryRetention = errors.New("history retention must not be negative")
+
+	// ErrInvalidHistoryInterval is returned when history is enabled without a positive interval.
+	ErrInvalidHistoryInterval = errors.New("history interval must be positive")
+
+	// ErrInvalidMetricsCategory is returned when a disabled metric category is not recognized.
+	ErrInvalidMetricsCategory = errors.New("disabled metrics must be from cpu, memory, disk, network")
+
+	// ErrInvalidDiskPattern is returned when a disk include or exclude pattern is not a valid glob.
+	ErrInvalidDiskPattern = errors.New("disk mount patterns must be valid globs")
+
+	// ErrInvalidAlertInterval is returned when alert rules are set without a positive interval.
+	ErrInvalidAlertInterval = errors.New("alert interval must be positive")
+
+	// ErrInvalidAlertWebhook is returned when the alert webhook is not an http(s) URL.
+	ErrInvalidAlertWebhook = errors.New("alert webhook must be an http or https URL")
+
+	// ErrInvalidDiagTCPTarget is returned when the connectivity check TCP target is not host:port.
+	ErrInvalidDiagTCPTarget = errors.New("diag TCP target must be host:port")
+
+	// ErrInvalidDiagHTTPURL is returned when the connectivity check URL is not an http(s) URL.
+	ErrInvalidDiagHTTPURL = errors.New("diag HTTP URL must be an http or https URL")
+
+	// ErrInvalidDiagTimeout is returned when the connectivity check timeout is not positive.
+	ErrInvalidDiagTimeout = errors.New("diag timeout must be positive")
+
+	// ErrInvalidDiskProbePaths is returned when a disk probe path is not an absolute path.
+	ErrInvalidDiskProbePaths = errors.New("disk probe paths must be absolute paths")
+
+	// ErrInvalidDiskProbeSize is returned when the disk probe size is out of range.
+	ErrInvalidDiskProbeSize = errors.New("disk probe size must be between 1 byte and 16 MiB")
+
+	// ErrInvalidMaxBodyBytes is returned when the request body cap is negative.
+	ErrInvalidMaxBodyBytes = errors.New("max body bytes must not be negative")
+
+	// ErrInvalidMaxLogBytes is returned when the log download cap is negative.
+	ErrInvalidMaxLogBytes = errors.New("max log bytes must not be negative")
+
+	// ErrInvalidLogLevel is returned when the log level is not recognized.
+	ErrInvalidLogLevel = errors.New("log level must be one of debug, info, warn, error")
+
+	// ErrInvalidLogFormat is returned when the log format is not recognized.
+	ErrInvalidLogFormat = errors.New("log format must be text or json")
 )
@@ -0,0 +1,144 @@
+// Package diagnostics checks the host's DNS resolution and outbound connectivity.
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Check kinds.
+const (
+	KindDNS  = "dns"
+	KindTCP  = "tcp"
+	KindHTTP = "http"
+)
+
+// Targets configures which checks run. Empty fields skip that check.
+type Targets struct {
+	DNSHost   string
+	TCPTarget string // host:port
+	HTTPURL   string
+}
+
+// Result is the outcome of a single check.
+type Result struct {
+	Kind      string  `json:"kind"`
+	Target    string  `json:"target"`
+	Success   bool    `json:"success"`
+	LatencyMs float64 `json:"latencyMs"`
+	// Detail holds the resolved addresses for DNS or the status for HTTP
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Checker runs connectivity checks against fixed targets.
+type Checker struct {
+	targets Targets
+	timeout time.Duration
+	client  *http.Client
+}
+
+// NewChecker creates a checker that bounds each check by timeout.
+func NewChecker(targets Targets, timeout time.Duration) *Checker {
+	return &Checker{
+		targets: targets,
+		timeout: timeout,
+		client: &http.Client{
+			// Report the target's own response rather than following it elsewhere
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Run performs the configured checks concurrently and returns their results
+// in DNS, TCP, HTTP order.
+func (c *Checker) Run(ctx context.Context) []Result {
+	var checks []func(context.Context) Result
+	if c.targets.DNSHost != "" {
+		checks = append(checks, c.checkDNS)
+	}
+	if c.targets.TCPTarget != "" {
+		checks = append(checks, c.checkTCP)
+	}
+	if c.targets.HTTPURL != "" {
+		checks = append(checks, c.checkHTTP)
+	}
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+			results[i] = check(ctx)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func (c *Checker) checkDNS(ctx context.Context) Result {
+	result := Result{Kind: KindDNS, Target: c.targets.DNSHost}
+	start := time.Now()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, c.targets.DNSHost)
+	result.finish(start, err)
+	if err == nil {
+		result.Detail = strings.Join(addrs, ", ")
+	}
+	return result
+}
+
+func (c *Checker) checkTCP(ctx context.Context) Result {
+	result := Result{Kind: KindTCP, Target: c.targets.TCPTarget}
+	start := time.Now()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.targets.TCPTarget)
+	result.finish(start, err)
+	if err == nil {
+		result.Detail = conn.RemoteAddr().String()
+		conn.Close()
+	}
+	return result
+}
+
+func (c *Checker) checkHTTP(ctx context.Context) Result {
+	result := Result{Kind: KindHTTP, Target: c.targets.HTTPURL}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.targets.HTTPURL, nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	if err == nil {
+		// Drain a little of the body so latency covers the first bytes
+		io.CopyN(io.Discard, resp.Body, 4096)
+		resp.Body.Close()
+		result.Detail = resp.Status
+		if resp.StatusCode >= 400 {
+			err = fmt.Errorf("unexpected status %s", resp.Status)
+		}
+	}
+	result.finish(start, err)
+	return result
+}
+
+// finish records the latency since start and the outcome err.
+func (r *Result) finish(start time.Time, err error) {
+	r.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	r.Success = err == nil
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
@@ -0,0 +1,168 @@
+package diagnostics
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// probeFilePattern names the temporary files written by disk probes.
+const probeFilePattern = ".servertui-probe-*"
+
+// DiskTarget is a directory to probe. ReadOnly targets are reported without
+// writing to them.
+type DiskTarget struct {
+	Path     string
+	ReadOnly bool
+}
+
+// DiskResult is the outcome of a write, fsync and read-back test in one directory.
+type DiskResult struct {
+	Path     string `json:"path"`
+	Success  bool   `json:"success"`
+	ReadOnly bool   `json:"readOnly"` // mounted or remounted read-only
+
+	// Timings of each step in milliseconds; LatencyMs is the total
+	WriteMs   float64 `json:"writeMs"`
+	SyncMs    float64 `json:"syncMs"`
+	ReadMs    float64 `json:"readMs"`
+	LatencyMs float64 `json:"latencyMs"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// ProbeDisks writes size bytes to a temporary file in each target, syncs it
+// to storage and reads it back, running the targets concurrently. A probe
+// still blocked after timeout (e.g. on a hung network mount) is reported as
+// failed; its file is removed whenever it completes.
+func ProbeDisks(ctx context.Context, targets []DiskTarget, size int, timeout time.Duration) []DiskResult {
+	results := make([]DiskResult, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = probeWithTimeout(ctx, target, size, timeout)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// probeWithTimeout runs probeDisk, giving up on it after timeout. File I/O
+// can't be canceled, so an abandoned probe finishes in the background.
+func probeWithTimeout(ctx context.Context, target DiskTarget, size int, timeout time.Duration) DiskResult {
+	if target.ReadOnly {
+		return DiskResult{Path: target.Path, ReadOnly: true, Error: "filesystem is mounted read-only"}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan DiskResult, 1)
+	start := time.Now()
+	go func() { done <- probeDisk(target.Path, size) }()
+
+	select {
+	case result := <-done:
+		return result
+	case <-ctx.Done():
+		result := DiskResult{Path: target.Path}
+		result.finish(start, fmt.Errorf("probe did not finish: %w", ctx.Err()))
+		return result
+	}
+}
+
+// probeDisk writes, syncs, reads back and removes a temporary file in dir.
+func probeDisk(dir string, size int) DiskResult {
+	result := DiskResult{Path: dir}
+	start := time.Now()
+
+	data := make([]byte, size)
+	rand.Read(data)
+
+	f, err := os.CreateTemp(dir, probeFilePattern)
+	if err != nil {
+		result.finish(start, err)
+		return result
+	}
+	defer os.Remove(f.Name())
+
+	step := time.Now()
+	_, err = f.Write(data)
+	result.WriteMs = millis(step)
+	if err != nil {
+		f.Close()
+		result.finish(start, err)
+		return result
+	}
+
+	step = time.Now()
+	err = f.Sync()
+	result.SyncMs = millis(step)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		result.finish(start, err)
+		return result
+	}
+
+	step = time.Now()
+	got, err := readUncached(f.Name())
+	result.ReadMs = millis(step)
+	if err == nil && !bytes.Equal(got, data) {
+		err = errors.New("data read back does not match what was written")
+	}
+	result.finish(start, err)
+	return result
+}
+
+// dropPageCache evicts a file's cached pages so the next read comes from
+// storage. It is nil on platforms that can't do so; there the read-back may
+// be served from memory and only verifies the write path.
+var dropPageCache func(f *os.File) error
+
+// readUncached reads the file at name, from storage where the platform
+// allows dropping its cached pages first.
+func readUncached(name string) ([]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if dropPageCache != nil {
+		if err := dropPageCache(f); err != nil {
+			return nil, fmt.Errorf("failed to drop cached pages: %w", err)
+		}
+	}
+	return io.ReadAll(f)
+}
+
+// finish records the total latency since start and the outcome err.
+func (r *DiskResult) finish(start time.Time, err error) {
+	r.LatencyMs = millis(start)
+	r.Success = err == nil
+	if err != nil {
+		r.ReadOnly = isReadOnly(err)
+		r.Error = err.Error()
+	}
+}
+
+// isReadOnly reports whether err came from writing to a read-only filesystem.
+func isReadOnly(err error) bool {
+	return errors.Is(err, syscall.EROFS)
+}
+
+// millis returns the time since start in fractional milliseconds.
+func millis(start time.Time) float64 {
+	return float64(time.Since(start).Microseconds()) / 1000
+}
@@ -0,0 +1,23 @@
+package diagnostics
+
+import (
+	"os"
+	"testing"
+)
+
+func TestProbeDisk(t *testing.T) {
+	dir := t.TempDir()
+	result := probeDisk(dir, 64<<10)
+	if !result.Success {
+		t.Fatalf("probe failed: %s", result.Error)
+	}
+
+	// The probe file is removed afterwards
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("probe left %d files behind", len(entries))
+	}
+}
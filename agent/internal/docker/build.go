@@ -0,0 +1,141 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types"
+)
+
+// ErrInvalidReference is returned when an image name or tag can't be parsed.
+var ErrInvalidReference = errors.New("invalid image reference")
+
+// BuildError is returned when the daemon reports that a build step failed.
+type BuildError struct {
+	Message string
+}
+
+func (e *BuildError) Error() string {
+	return "build failed: " + e.Message
+}
+
+// buildMessage is one JSON message of the daemon's build output.
+type buildMessage struct {
+	Stream      string           `json:"stream"`
+	Status      string           `json:"status"`
+	ID          string           `json:"id"`
+	Aux         *json.RawMessage `json:"aux"`
+	Error       string           `json:"error"`
+	ErrorDetail *struct {
+		Message string `json:"message"`
+	} `json:"errorDetail"`
+}
+
+// BuildOptions configures an image build.
+type BuildOptions struct {
+	// Tags names the built image, e.g. "myapp:latest"
+	Tags []string
+
+	// Dockerfile is the Dockerfile's path within the context (default "Dockerfile")
+	Dockerfile string
+
+	// NoCache disables the build cache and Pull always pulls base images
+	NoCache bool
+	Pull    bool
+}
+
+// TagImage creates the tag target referring to the image source, which may
+// be an ID or a name.
+func (m *Manager) TagImage(ctx context.Context, source, target string) error {
+	if _, err := reference.ParseAnyReference(source); err != nil {
+		return fmt.Errorf("%w %q: %v", ErrInvalidReference, source, err)
+	}
+	if _, err := reference.ParseNormalizedNamed(target); err != nil {
+		return fmt.Errorf("%w %q: %v", ErrInvalidReference, target, err)
+	}
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+	return m.client.ImageTag(ctx, source, target)
+}
+
+// BuildImage builds an image from buildContext, a tar archive holding the
+// Dockerfile and the files it uses, sending the build output to out line by
+// line. out is closed when the build ends. It returns the ID of the built
+// image, or a *BuildError if a build step failed.
+func (m *Manager) BuildImage(ctx context.Context, buildContext io.Reader, opts BuildOptions, out chan<- string) (string, error) {
+	defer close(out)
+
+	for _, tag := range opts.Tags {
+		if _, err := reference.ParseNormalizedNamed(tag); err != nil {
+			return "", fmt.Errorf("%w %q: %v", ErrInvalidReference, tag, err)
+		}
+	}
+
+	resp, err := m.client.ImageBuild(ctx, buildContext, types.ImageBuildOptions{
+		Tags:       opts.Tags,
+		Dockerfile: opts.Dockerfile,
+		NoCache:    opts.NoCache,
+		PullParent: opts.Pull,
+		Remove:     true,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	send := func(line string) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case out <- line:
+			return nil
+		}
+	}
+
+	var imageID string
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg buildMessage
+		if err := dec.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			return "", err
+		}
+
+		switch {
+		case msg.ErrorDetail != nil:
+			return "", &BuildError{Message: msg.ErrorDetail.Message}
+		case msg.Error != "":
+			return "", &BuildError{Message: msg.Error}
+		case msg.Aux != nil:
+			var aux types.BuildResult
+			if json.Unmarshal(*msg.Aux, &aux) == nil && aux.ID != "" {
+				imageID = aux.ID
+			}
+		case msg.Stream != "":
+			for _, line := range strings.Split(strings.TrimSuffix(msg.Stream, "\n"), "\n") {
+				if err := send(line); err != nil {
+					return "", err
+				}
+			}
+		case msg.Status != "":
+			line := msg.Status
+			if msg.ID != "" {
+				line = msg.ID + ": " + line
+			}
+			if err := send(line); err != nil {
+				return "", err
+			}
+		}
+	}
+	return imageID, nil
+}
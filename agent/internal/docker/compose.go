@@ -0,0 +1,153 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Labels Docker Compose sets on the containers it creates.
+const (
+	ComposeProjectLabel = "com.docker.compose.project"
+	ComposeServiceLabel = "com.docker.compose.service"
+)
+
+// UngroupedProject is the project name used for containers not created by Compose.
+const UngroupedProject = "ungrouped"
+
+// ComposeProject is the set of containers belonging to one Compose project.
+type ComposeProject struct {
+	Name       string      `json:"name"`
+	Running    int         `json:"running"`
+	Total      int         `json:"total"`
+	Containers []Container `json:"containers"`
+}
+
+// GroupByProject groups containers by Compose project, sorted by name with
+// the ungrouped containers last.
+func GroupByProject(containers []Container) []ComposeProject {
+	index := make(map[string]int)
+	var projects []ComposeProject
+	for _, c := range containers {
+		name := c.Project
+		if name == "" {
+			name = UngroupedProject
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(projects)
+			index[name] = i
+			projects = append(projects, ComposeProject{Name: name})
+		}
+		p := &projects[i]
+		p.Containers = append(p.Containers, c)
+		p.Total++
+		if c.State == "running" {
+			p.Running++
+		}
+	}
+
+	sort.Slice(projects, func(i, j int) bool {
+		a, b := projects[i].Name, projects[j].Name
+		if (a == UngroupedProject) != (b == UngroupedProject) {
+			return b == UngroupedProject
+		}
+		return a < b
+	})
+	return projects
+}
+
+// actionWorkers bounds how many containers a project or batch action
+// operates on at once.
+const actionWorkers = 4
+
+// MaxBatchContainers bounds how many containers one BatchAction may name.
+const MaxBatchContainers = 100
+
+// ContainerActionResult is the outcome of an action on one container.
+type ContainerActionResult struct {
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ProjectAction runs start, stop or restart on every container in a Compose
+// project, a few at a time, and returns a result per container. It returns an
+// error only if the action is unknown or the containers can't be listed.
+func (m *Manager) ProjectAction(ctx context.Context, project, action string) ([]ContainerActionResult, error) {
+	run, err := m.containerAction(action)
+	if err != nil {
+		return nil, err
+	}
+
+	containers, err := m.ListContainers(ctx, ContainerFilter{Project: project})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ContainerActionResult, len(containers))
+	for i, c := range containers {
+		results[i] = ContainerActionResult{ID: c.ID, Name: c.Name}
+	}
+	runActions(ctx, run, results)
+	return results, nil
+}
+
+// BatchAction runs start, stop or restart on each of ids, a few at a time,
+// and returns a result per container in the order given. A failure on one
+// container doesn't stop the others. It returns an error only if the action
+// is unknown.
+func (m *Manager) BatchAction(ctx context.Context, action string, ids []string) ([]ContainerActionResult, error) {
+	run, err := m.containerAction(action)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ContainerActionResult, len(ids))
+	for i, id := range ids {
+		results[i] = ContainerActionResult{ID: id}
+	}
+	runActions(ctx, run, results)
+	return results, nil
+}
+
+// containerAction returns the method that performs action on a container.
+func (m *Manager) containerAction(action string) (func(context.Context, string) error, error) {
+	switch action {
+	case "start":
+		return m.StartContainer, nil
+	case "stop":
+		return m.StopContainer, nil
+	case "restart":
+		return m.RestartContainer, nil
+	}
+	return nil, fmt.Errorf("invalid action %q: must be start, stop or restart", action)
+}
+
+// runActions calls run on the container of each result using up to
+// actionWorkers goroutines, recording success or the error in the result.
+func runActions(ctx context.Context, run func(context.Context, string) error, results []ContainerActionResult) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(actionWorkers, len(results)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				res := &results[i]
+				res.Success = true
+				if err := run(ctx, res.ID); err != nil {
+					res.Success = false
+					res.Error = err.Error()
+				}
+			}
+		}()
+	}
+	for i := range results {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
@@ -2,16 +2,24 @@
 package docker
 
 import (
-	"bufio"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/distribution/reference"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 )
 
 // Container represents a Docker container.
@@ -23,6 +31,11 @@ type Container struct {
 	State   string   `json:"state"`
 	Ports   []string `json:"ports"`
 	Created string   `json:"created"`
+	// Health is healthy, unhealthy or starting; empty without a healthcheck
+	Health string `json:"health,omitempty"`
+	// Project and Service come from Docker Compose labels
+	Project string `json:"project,omitempty"`
+	Service string `json:"service,omitempty"`
 }
 
 // ContainerDetails represents detailed container information.
@@ -37,6 +50,22 @@ type ContainerDetails struct {
 	IPAddress string            `json:"ipAddress"`
 	Pid       int               `json:"pid"`
 	Labels    map[string]string `json:"labels"`
+	// Health is healthy, unhealthy or starting; empty without a healthcheck
+	Health string `json:"health,omitempty"`
+
+	// StartedAt and FinishedAt are when the container last started and
+	// exited, empty if it never has
+	StartedAt  string `json:"startedAt,omitempty"`
+	FinishedAt string `json:"finishedAt,omitempty"`
+	// ExitCode is the last exit code, set only when the container isn't running
+	ExitCode *int `json:"exitCode,omitempty"`
+
+	// CPULimit is the CPU limit in cores (0 means unlimited)
+	CPULimit float64 `json:"cpuLimit"`
+	// MemoryLimit is the memory limit in bytes (0 means unlimited)
+	MemoryLimit       int64  `json:"memoryLimit"`
+	RestartPolicy     string `json:"restartPolicy"`
+	RestartMaxRetries int    `json:"restartMaxRetries"`
 }
 
 // Image represents a Docker image.
@@ -46,6 +75,12 @@ type Image struct {
 	Tag        string `json:"tag"`
 	Size       int64  `json:"size"`
 	Created    string `json:"created"`
+	// Tags lists every repo:tag reference; Repository and Tag hold the first one
+	Tags []string `json:"tags"`
+	// Digest is the content digest from the image's first repo digest, if pushed or pulled
+	Digest string `json:"digest,omitempty"`
+
+	createdUnix int64
 }
 
 // Status represents the overall Docker status.
@@ -53,22 +88,76 @@ type Status struct {
 	Installed  bool        `json:"installed"`
 	Containers []Container `json:"containers"`
 	Images     []Image     `json:"images"`
+
+	// TotalContainers and TotalImages count every container and image, which
+	// may be more than the lists hold once they are paged
+	TotalContainers int `json:"totalContainers"`
+	TotalImages     int `json:"totalImages"`
+}
+
+// Info describes the Docker daemon.
+type Info struct {
+	Version           string `json:"version"`
+	APIVersion        string `json:"apiVersion"`
+	MinAPIVersion     string `json:"minApiVersion"`
+	OS                string `json:"os"`
+	Arch              string `json:"arch"`
+	KernelVersion     string `json:"kernelVersion"`
+	OperatingSystem   string `json:"operatingSystem"`
+	StorageDriver     string `json:"storageDriver"`
+	CgroupVersion     string `json:"cgroupVersion"` // "1" or "2"; empty on Windows daemons
+	RootDir           string `json:"rootDir"`
+	Containers        int    `json:"containers"`
+	ContainersRunning int    `json:"containersRunning"`
+	ContainersPaused  int    `json:"containersPaused"`
+	ContainersStopped int    `json:"containersStopped"`
+	Images            int    `json:"images"`
+	CPUs              int    `json:"cpus"`
+	MemoryTotal       int64  `json:"memoryTotal"`
 }
 
 // Manager handles Docker operations.
 type Manager struct {
-	client *client.Client
+	client  *client.Client
+	timeout time.Duration
+
+	// prevCPU holds each container's last CPU counters for GetContainerStats
+	statsMu sync.Mutex
+	prevCPU map[string]types.CPUStats
+}
+
+// Options selects the Docker daemon to connect to. Empty fields fall back
+// to the DOCKER_HOST and DOCKER_CERT_PATH environment variables.
+type Options struct {
+	// Host is the daemon address, e.g. unix:///var/run/docker.sock or tcp://host:2376
+	Host string
+
+	// TLSCAPath, TLSCertPath and TLSKeyPath configure TLS to a remote daemon
+	TLSCAPath   string
+	TLSCertPath string
+	TLSKeyPath  string
+
+	// Timeout bounds each API call other than log, event and build streams (0 disables)
+	Timeout time.Duration
 }
 
 // NewManager creates a new Docker manager.
 // Returns nil if Docker is not available.
-func NewManager() (*Manager, error) {
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithVersion("1.44"))
+func NewManager(opts Options) (*Manager, error) {
+	clientOpts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+	if opts.Host != "" {
+		clientOpts = append(clientOpts, client.WithHost(opts.Host))
+	}
+	if opts.TLSCAPath != "" || opts.TLSCertPath != "" {
+		clientOpts = append(clientOpts, client.WithTLSClientConfig(opts.TLSCAPath, opts.TLSCertPath, opts.TLSKeyPath))
+	}
+
+	cli, err := client.NewClientWithOpts(clientOpts...)
 	if err != nil {
 		return nil, err
 	}
 
-	// Test connection
+	// Test connection; the ping also negotiates the API version
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -78,7 +167,16 @@ func NewManager() (*Manager, error) {
 		return nil, err
 	}
 
-	return &Manager{client: cli}, nil
+	return &Manager{client: cli, timeout: opts.Timeout}, nil
+}
+
+// withTimeout bounds a single API call by the manager's timeout, so a hung
+// daemon can't block the caller indefinitely.
+func (m *Manager) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if m.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, m.timeout)
 }
 
 // Close closes the Docker client connection.
@@ -89,9 +187,28 @@ func (m *Manager) Close() error {
 	return nil
 }
 
+// APIVersion returns the Docker API version negotiated with the daemon.
+func (m *Manager) APIVersion() string {
+	return m.client.ClientVersion()
+}
+
+// IsNotFound reports whether err is the daemon saying the container or
+// image doesn't exist.
+func IsNotFound(err error) bool {
+	return client.IsErrNotFound(err)
+}
+
+// Ping checks that the Docker daemon is reachable.
+func (m *Manager) Ping(ctx context.Context) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+	_, err := m.client.Ping(ctx)
+	return err
+}
+
 // GetStatus returns the current Docker status including containers and images.
 func (m *Manager) GetStatus(ctx context.Context) (*Status, error) {
-	containers, err := m.ListContainers(ctx)
+	containers, err := m.ListContainers(ctx, ContainerFilter{})
 	if err != nil {
 		return nil, err
 	}
@@ -102,21 +219,107 @@ func (m *Manager) GetStatus(ctx context.Context) (*Status, error) {
 	}
 
 	return &Status{
-		Installed:  true,
-		Containers: containers,
-		Images:     images,
+		Installed:       true,
+		Containers:      containers,
+		Images:          images,
+		TotalContainers: len(containers),
+		TotalImages:     len(images),
 	}, nil
 }
 
-// ListContainers lists all Docker containers.
-func (m *Manager) ListContainers(ctx context.Context) ([]Container, error) {
-	containers, err := m.client.ContainerList(ctx, types.ContainerListOptions{All: true})
+// GetInfo returns the daemon's version and system information.
+func (m *Manager) GetInfo(ctx context.Context) (*Info, error) {
+	version, err := retryRead(ctx, m, m.client.ServerVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := retryRead(ctx, m, m.client.Info)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Info{
+		Version:           version.Version,
+		APIVersion:        version.APIVersion,
+		MinAPIVersion:     version.MinAPIVersion,
+		OS:                version.Os,
+		Arch:              version.Arch,
+		KernelVersion:     version.KernelVersion,
+		OperatingSystem:   info.OperatingSystem,
+		StorageDriver:     info.Driver,
+		CgroupVersion:     info.CgroupVersion,
+		RootDir:           info.DockerRootDir,
+		Containers:        info.Containers,
+		ContainersRunning: info.ContainersRunning,
+		ContainersPaused:  info.ContainersPaused,
+		ContainersStopped: info.ContainersStopped,
+		Images:            info.Images,
+		CPUs:              info.NCPU,
+		MemoryTotal:       info.MemTotal,
+	}, nil
+}
+
+// ContainerFilter narrows the containers returned by ListContainers.
+// The zero value matches every container.
+type ContainerFilter struct {
+	// State is a container state such as "running" or "exited"; "" or "all" matches any
+	State string
+	// Labels are "key" or "key=value" selectors that must all match
+	Labels []string
+	// Project is a Compose project name; UngroupedProject matches containers
+	// that don't belong to one
+	Project string
+}
+
+// validStates are the container states accepted by ContainerFilter.
+var validStates = map[string]bool{
+	"created": true, "restarting": true, "running": true, "removing": true,
+	"paused": true, "exited": true, "dead": true,
+}
+
+// Validate checks that the filter's state is one Docker understands.
+func (f ContainerFilter) Validate() error {
+	if f.State != "" && f.State != "all" && !validStates[f.State] {
+		return fmt.Errorf("invalid container state %q", f.State)
+	}
+	return nil
+}
+
+// args converts the filter to Docker API filter arguments.
+func (f ContainerFilter) args() filters.Args {
+	args := filters.NewArgs()
+	if f.State != "" && f.State != "all" {
+		args.Add("status", f.State)
+	}
+	for _, label := range f.Labels {
+		args.Add("label", label)
+	}
+	if f.Project != "" && f.Project != UngroupedProject {
+		args.Add("label", ComposeProjectLabel+"="+f.Project)
+	}
+	return args
+}
+
+// ListContainers lists Docker containers matching the filter.
+func (m *Manager) ListContainers(ctx context.Context, filter ContainerFilter) ([]Container, error) {
+	containers, err := retryRead(ctx, m, func(ctx context.Context) ([]types.Container, error) {
+		return m.client.ContainerList(ctx, types.ContainerListOptions{
+			All:     true,
+			Filters: filter.args(),
+		})
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	result := make([]Container, 0, len(containers))
 	for _, c := range containers {
+		project := c.Labels[ComposeProjectLabel]
+		if filter.Project == UngroupedProject && project != "" {
+			continue
+		}
+
 		// Format ports
 		var ports []string
 		for _, p := range c.Ports {
@@ -138,16 +341,47 @@ func (m *Manager) ListContainers(ctx context.Context) ([]Container, error) {
 			Status:  c.Status,
 			State:   c.State,
 			Ports:   ports,
-			Created: time.Unix(c.Created, 0).Format(time.RFC3339),
+			Created: time.Unix(c.Created, 0).UTC().Format(time.RFC3339),
+			Health:  healthFromStatus(c.Status),
+			Project: project,
+			Service: c.Labels[ComposeServiceLabel],
 		})
 	}
 
 	return result, nil
 }
 
+// formatDockerTime reformats a timestamp from the Docker API as RFC3339,
+// matching the list endpoints. Docker reports unset times as the zero time,
+// which becomes "".
+func formatDockerTime(s string) string {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// healthFromStatus extracts the health state from a container list status
+// such as "Up 2 hours (healthy)" or "Up 3 seconds (health: starting)". The
+// list API has no structured health field, unlike inspect.
+func healthFromStatus(status string) string {
+	switch {
+	case strings.HasSuffix(status, "("+types.Unhealthy+")"):
+		return types.Unhealthy
+	case strings.HasSuffix(status, "("+types.Healthy+")"):
+		return types.Healthy
+	case strings.HasSuffix(status, "(health: "+types.Starting+")"):
+		return types.Starting
+	}
+	return ""
+}
+
 // ListImages lists all Docker images.
 func (m *Manager) ListImages(ctx context.Context) ([]Image, error) {
-	images, err := m.client.ImageList(ctx, types.ImageListOptions{})
+	images, err := retryRead(ctx, m, func(ctx context.Context) ([]types.ImageSummary, error) {
+		return m.client.ImageList(ctx, types.ImageListOptions{})
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -166,37 +400,135 @@ func (m *Manager) ListImages(ctx context.Context) ([]Image, error) {
 			}
 		}
 
+		tags := make([]string, 0, len(img.RepoTags))
+		for _, t := range img.RepoTags {
+			if t != "<none>:<none>" {
+				tags = append(tags, t)
+			}
+		}
+
+		digest := ""
+		if len(img.RepoDigests) > 0 {
+			if _, d, ok := strings.Cut(img.RepoDigests[0], "@"); ok {
+				digest = d
+			}
+		}
+
 		result = append(result, Image{
 			ID:         img.ID[7:19], // Short ID (skip "sha256:")
 			Repository: repo,
 			Tag:        tag,
 			Size:       img.Size,
-			Created:    time.Unix(img.Created, 0).Format(time.RFC3339),
+			Created:    time.Unix(img.Created, 0).UTC().Format(time.RFC3339),
+			Tags:       tags,
+			Digest:     digest,
+
+			createdUnix: img.Created,
 		})
 	}
 
 	return result, nil
 }
 
+// SortImages sorts images in place, largest or newest first.
+// key is "size" or "created"; an empty key leaves the order unchanged.
+func SortImages(images []Image, key string) error {
+	switch key {
+	case "":
+	case "size":
+		sort.SliceStable(images, func(i, j int) bool { return images[i].Size > images[j].Size })
+	case "created":
+		sort.SliceStable(images, func(i, j int) bool { return images[i].createdUnix > images[j].createdUnix })
+	default:
+		return fmt.Errorf("invalid image sort %q: expected size or created", key)
+	}
+	return nil
+}
+
 // StartContainer starts a container by ID.
 func (m *Manager) StartContainer(ctx context.Context, containerID string) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
 	return m.client.ContainerStart(ctx, containerID, types.ContainerStartOptions{})
 }
 
 // StopContainer stops a container by ID.
 func (m *Manager) StopContainer(ctx context.Context, containerID string) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
 	stopTimeout := 10 // seconds
 	return m.client.ContainerStop(ctx, containerID, container.StopOptions{Timeout: &stopTimeout})
 }
 
+// RestartContainer restarts a container by ID.
+func (m *Manager) RestartContainer(ctx context.Context, containerID string) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+	stopTimeout := 10 // seconds
+	return m.client.ContainerRestart(ctx, containerID, container.StopOptions{Timeout: &stopTimeout})
+}
+
+// killSignals are the signal names accepted by KillContainer.
+var killSignals = map[string]bool{
+	"SIGKILL": true, "SIGTERM": true, "SIGINT": true, "SIGHUP": true,
+	"SIGQUIT": true, "SIGUSR1": true, "SIGUSR2": true, "SIGSTOP": true,
+	"SIGCONT": true, "SIGABRT": true, "SIGALRM": true, "SIGWINCH": true,
+}
+
+// NormalizeSignal validates a signal name and returns it in "SIGNAME" form.
+// The "SIG" prefix is optional and case is ignored; empty means SIGKILL.
+func NormalizeSignal(signal string) (string, error) {
+	if signal == "" {
+		return "SIGKILL", nil
+	}
+	name := strings.ToUpper(signal)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	if !killSignals[name] {
+		return "", fmt.Errorf("unsupported signal %q", signal)
+	}
+	return name, nil
+}
+
+// KillContainer sends a signal to a container's main process.
+// The signal must already be normalized with NormalizeSignal.
+func (m *Manager) KillContainer(ctx context.Context, containerID, signal string) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+	return m.client.ContainerKill(ctx, containerID, signal)
+}
+
 // formatPort formats a port binding for display.
 func formatPort(p types.Port) string {
 	return fmt.Sprintf("%d->%d/%s", p.PublicPort, p.PrivatePort, p.Type)
 }
 
+// InspectContainerRaw returns the daemon's inspect JSON for a container as-is.
+func (m *Manager) InspectContainerRaw(ctx context.Context, containerID string) ([]byte, error) {
+	return retryRead(ctx, m, func(ctx context.Context) ([]byte, error) {
+		_, raw, err := m.client.ContainerInspectWithRaw(ctx, containerID, false)
+		return raw, err
+	})
+}
+
+// InspectImageRaw returns the daemon's inspect JSON for an image, given by
+// ID or name, as-is.
+func (m *Manager) InspectImageRaw(ctx context.Context, image string) ([]byte, error) {
+	if _, err := reference.ParseAnyReference(image); err != nil {
+		return nil, fmt.Errorf("%w %q: %v", ErrInvalidReference, image, err)
+	}
+	return retryRead(ctx, m, func(ctx context.Context) ([]byte, error) {
+		_, raw, err := m.client.ImageInspectWithRaw(ctx, image)
+		return raw, err
+	})
+}
+
 // GetContainerDetails returns detailed information about a specific container.
 func (m *Manager) GetContainerDetails(ctx context.Context, containerID string) (*ContainerDetails, error) {
-	c, err := m.client.ContainerInspect(ctx, containerID)
+	c, err := retryRead(ctx, m, func(ctx context.Context) (types.ContainerJSON, error) {
+		return m.client.ContainerInspect(ctx, containerID)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -220,76 +552,326 @@ func (m *Manager) GetContainerDetails(ctx context.Context, containerID string) (
 		ipAddress = c.NetworkSettings.IPAddress
 	}
 
-	return &ContainerDetails{
+	details := &ContainerDetails{
 		ID:        c.ID[:12],
 		Name:      name,
 		Image:     c.Config.Image,
 		Status:    c.State.Status,
 		State:     c.State.Status,
 		Ports:     ports,
-		Created:   c.Created,
+		Created:   formatDockerTime(c.Created),
 		IPAddress: ipAddress,
 		Pid:       c.State.Pid,
 		Labels:    c.Config.Labels,
-	}, nil
+
+		RestartPolicy: "no",
+	}
+	if h := c.State.Health; h != nil && h.Status != types.NoHealthcheck {
+		details.Health = h.Status
+	}
+	details.StartedAt = formatDockerTime(c.State.StartedAt)
+	details.FinishedAt = formatDockerTime(c.State.FinishedAt)
+	if !c.State.Running {
+		exitCode := c.State.ExitCode
+		details.ExitCode = &exitCode
+	}
+
+	if hc := c.HostConfig; hc != nil {
+		switch {
+		case hc.NanoCPUs > 0:
+			details.CPULimit = float64(hc.NanoCPUs) / 1e9
+		case hc.CPUQuota > 0:
+			period := hc.CPUPeriod
+			if period == 0 {
+				period = 100000 // Docker's default CFS period in microseconds
+			}
+			details.CPULimit = float64(hc.CPUQuota) / float64(period)
+		}
+		details.MemoryLimit = hc.Memory
+		if hc.RestartPolicy.Name != "" {
+			details.RestartPolicy = hc.RestartPolicy.Name
+		}
+		details.RestartMaxRetries = hc.RestartPolicy.MaximumRetryCount
+	}
+
+	return details, nil
 }
 
 // LogsOptions contains options for streaming container logs.
+// Since and Until accept anything ParseLogTime does.
 type LogsOptions struct {
 	Follow     bool
 	Tail       string
+	Since      string
+	Until      string
 	Timestamps bool
+	// Filter, if set, drops lines that don't match
+	Filter *LogFilter
+}
+
+// LogFilter selects log lines by substring or regular expression.
+type LogFilter struct {
+	re     *regexp.Regexp
+	invert bool
+}
+
+// NewLogFilter compiles a filter for pattern, which is a literal substring
+// unless regex is set. With invert, lines that don't match are kept instead.
+func NewLogFilter(pattern string, regex, ignoreCase, invert bool) (*LogFilter, error) {
+	if !regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid grep pattern: %w", err)
+	}
+	return &LogFilter{re: re, invert: invert}, nil
+}
+
+// keep reports whether line passes the filter. Timestamped lines are matched
+// without their leading timestamp.
+func (f *LogFilter) keep(line string, timestamps bool) bool {
+	if f == nil {
+		return true
+	}
+	if timestamps {
+		if _, rest, ok := strings.Cut(line, " "); ok {
+			line = rest
+		}
+	}
+	return f.re.MatchString(line) != f.invert
+}
+
+// ParseLogTime normalizes a log time filter to a Unix timestamp understood by
+// the Docker API. It accepts RFC3339 timestamps and durations relative to now,
+// so "10m" means ten minutes ago. An empty value is returned unchanged.
+func ParseLogTime(value string, now time.Time) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return strconv.FormatInt(t.Unix(), 10), nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return strconv.FormatInt(now.Add(-d).Unix(), 10), nil
+	}
+
+	return "", fmt.Errorf("invalid time %q: expected RFC3339 timestamp or duration like 10m", value)
 }
 
-// StreamLogs streams container logs to the provided channel.
-// The channel is closed when streaming is complete or an error occurs.
-func (m *Manager) StreamLogs(ctx context.Context, containerID string, opts LogsOptions, logChan chan<- string) error {
+// Log stream names used in LogLine.
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// LogLine is a single container log line tagged with its source stream.
+type LogLine struct {
+	Stream string `json:"stream"`
+	Line   string `json:"line"`
+}
+
+// errLogLimit is returned by limitWriter once its byte budget is spent.
+var errLogLimit = errors.New("log size limit reached")
+
+// openLogs opens the container's log stream and reports whether the container
+// uses a TTY. TTY logs are raw; all others are multiplexed with 8-byte headers.
+func (m *Manager) openLogs(ctx context.Context, containerID string, opts LogsOptions) (io.ReadCloser, bool, error) {
+	// Only the inspect is bounded; the log stream may run as long as the caller wants
+	info, err := retryRead(ctx, m, func(ctx context.Context) (types.ContainerJSON, error) {
+		return m.client.ContainerInspect(ctx, containerID)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
 	options := types.ContainerLogsOptions{
 		ShowStdout: true,
 		ShowStderr: true,
 		Follow:     opts.Follow,
 		Tail:       opts.Tail,
+		Since:      opts.Since,
+		Until:      opts.Until,
 		Timestamps: opts.Timestamps,
 	}
 
 	reader, err := m.client.ContainerLogs(ctx, containerID, options)
 	if err != nil {
+		return nil, false, err
+	}
+	return reader, info.Config != nil && info.Config.Tty, nil
+}
+
+// copyStreams demultiplexes a log stream into stdout and stderr.
+// TTY streams carry no headers and are copied to stdout as-is.
+func copyStreams(reader io.Reader, tty bool, stdout, stderr io.Writer) error {
+	if tty {
+		_, err := io.Copy(stdout, reader)
 		return err
 	}
-	defer reader.Close()
+	_, err := stdcopy.StdCopy(stdout, stderr, reader)
+	return err
+}
 
-	scanner := bufio.NewScanner(reader)
-	for scanner.Scan() {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case logChan <- scanner.Text():
+// maxLogLineBytes bounds a buffered log line; longer lines are split, so a
+// container writing without newlines can't grow the buffer without limit.
+const maxLogLineBytes = 64 * 1024
+
+// lineWriter splits written bytes into lines and passes each to emit.
+// Lines longer than maxLogLineBytes are split.
+type lineWriter struct {
+	buf  []byte
+	emit func(line string) error
+}
+
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	lw.buf = append(lw.buf, p...)
+	for {
+		i := bytes.IndexByte(lw.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimSuffix(string(lw.buf[:i]), "\r")
+		lw.buf = lw.buf[i+1:]
+		if err := lw.emit(line); err != nil {
+			return 0, err
 		}
 	}
+	for len(lw.buf) >= maxLogLineBytes {
+		line := string(lw.buf[:maxLogLineBytes])
+		lw.buf = lw.buf[maxLogLineBytes:]
+		if err := lw.emit(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
 
-	return scanner.Err()
+// flush emits any trailing partial line.
+func (lw *lineWriter) flush() error {
+	if len(lw.buf) == 0 {
+		return nil
+	}
+	line := string(lw.buf)
+	lw.buf = nil
+	return lw.emit(line)
 }
 
-// GetContainerLogs returns recent container logs as a single string.
-func (m *Manager) GetContainerLogs(ctx context.Context, containerID string, tail string) (string, error) {
-	options := types.ContainerLogsOptions{
-		ShowStdout: true,
-		ShowStderr: true,
-		Follow:     false,
-		Tail:       tail,
-		Timestamps: true,
+// copyFilteredStreams copies the lines that pass opts.Filter to w, splitting
+// stdout and stderr separately so partial lines from each don't interleave.
+func copyFilteredStreams(reader io.Reader, tty bool, w io.Writer, opts LogsOptions) error {
+	emit := func(line string) error {
+		if !opts.Filter.keep(line, opts.Timestamps) {
+			return nil
+		}
+		_, err := io.WriteString(w, line+"\n")
+		return err
 	}
+	stdout, stderr := &lineWriter{emit: emit}, &lineWriter{emit: emit}
 
-	reader, err := m.client.ContainerLogs(ctx, containerID, options)
+	if err := copyStreams(reader, tty, stdout, stderr); err != nil {
+		return err
+	}
+	if err := stdout.flush(); err != nil {
+		return err
+	}
+	return stderr.flush()
+}
+
+// limitWriter writes at most remaining bytes to w, then fails with errLogLimit.
+type limitWriter struct {
+	w         io.Writer
+	remaining int64
+}
+
+func (lw *limitWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) <= lw.remaining {
+		n, err := lw.w.Write(p)
+		lw.remaining -= int64(n)
+		return n, err
+	}
+	n, err := lw.w.Write(p[:lw.remaining])
+	lw.remaining -= int64(n)
 	if err != nil {
-		return "", err
+		return n, err
+	}
+	return n, errLogLimit
+}
+
+// StreamLogs streams container logs to the provided channel, one line at a
+// time, with stdout and stderr demultiplexed.
+func (m *Manager) StreamLogs(ctx context.Context, containerID string, opts LogsOptions, logChan chan<- LogLine) error {
+	reader, tty, err := m.openLogs(ctx, containerID, opts)
+	if err != nil {
+		return err
 	}
 	defer reader.Close()
 
-	logs, err := io.ReadAll(reader)
+	emitter := func(stream string) *lineWriter {
+		return &lineWriter{emit: func(line string) error {
+			if !opts.Filter.keep(line, opts.Timestamps) {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case logChan <- LogLine{Stream: stream, Line: line}:
+				return nil
+			}
+		}}
+	}
+	stdout, stderr := emitter(StreamStdout), emitter(StreamStderr)
+
+	if err := copyStreams(reader, tty, stdout, stderr); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	if err := stdout.flush(); err != nil {
+		return err
+	}
+	return stderr.flush()
+}
+
+// CopyLogs writes demultiplexed container logs to w, stopping after maxBytes
+// bytes. It reports whether the output was truncated. A maxBytes of 0 means no limit.
+func (m *Manager) CopyLogs(ctx context.Context, containerID string, opts LogsOptions, w io.Writer, maxBytes int64) (bool, error) {
+	reader, tty, err := m.openLogs(ctx, containerID, opts)
 	if err != nil {
-		return "", err
+		return false, err
 	}
+	defer reader.Close()
 
-	return string(logs), nil
+	if maxBytes > 0 {
+		w = &limitWriter{w: w, remaining: maxBytes}
+	}
+
+	if opts.Filter != nil {
+		err = copyFilteredStreams(reader, tty, w, opts)
+	} else {
+		err = copyStreams(reader, tty, w, w)
+	}
+	if errors.Is(err, errLogLimit) {
+		return true, nil
+	}
+	return false, err
+}
+
+// GetContainerLogs returns recent container logs as a single string,
+// reading at most maxBytes bytes (0 means no limit).
+func (m *Manager) GetContainerLogs(ctx context.Context, containerID string, tail string, maxBytes int64) (string, error) {
+	var buf strings.Builder
+	opts := LogsOptions{
+		Tail:       tail,
+		Timestamps: true,
+	}
+	if _, err := m.CopyLogs(ctx, containerID, opts, &buf, maxBytes); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
 }
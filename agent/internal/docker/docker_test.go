@@ -0,0 +1,136 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/client"
+)
+
+func TestLineWriterSplitsLongLines(t *testing.T) {
+	var lines []string
+	lw := &lineWriter{emit: func(line string) error {
+		lines = append(lines, line)
+		return nil
+	}}
+
+	long := strings.Repeat("x", 2*maxLogLineBytes+10)
+	for i := 0; i < len(long); i += 1000 {
+		lw.Write([]byte(long[i:min(i+1000, len(long))]))
+		if len(lw.buf) >= maxLogLineBytes {
+			t.Fatalf("buffered %d bytes, want < %d", len(lw.buf), maxLogLineBytes)
+		}
+	}
+	lw.Write([]byte("\nshort\r\n"))
+
+	want := []int{maxLogLineBytes, maxLogLineBytes, 10, 5}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(lines), len(want))
+	}
+	for i, n := range want {
+		if len(lines[i]) != n {
+			t.Errorf("line %d has %d bytes, want %d", i, len(lines[i]), n)
+		}
+	}
+}
+
+// newTestManager returns a Manager talking to a fake daemon served by handler.
+func newTestManager(t *testing.T, handler http.Handler, timeout time.Duration) *Manager {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	cli, err := client.NewClientWithOpts(client.WithHost("tcp://"+srv.Listener.Addr().String()), client.WithVersion("1.43"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cli.Close() })
+	return &Manager{client: cli, timeout: timeout}
+}
+
+// stallingDaemon answers nothing until the request is canceled, counting requests.
+func stallingDaemon(requests *atomic.Int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-r.Context().Done()
+	})
+}
+
+func TestTimeoutBoundsHungDaemon(t *testing.T) {
+	var requests atomic.Int32
+	m := newTestManager(t, stallingDaemon(&requests), 100*time.Millisecond)
+
+	calls := map[string]func(context.Context) error{
+		"start": func(ctx context.Context) error { return m.StartContainer(ctx, "web") },
+		"stop":  func(ctx context.Context) error { return m.StopContainer(ctx, "web") },
+		"kill":  func(ctx context.Context) error { return m.KillContainer(ctx, "web", "SIGKILL") },
+		"ping":  m.Ping,
+		"tag":   func(ctx context.Context) error { return m.TagImage(ctx, "nginx", "nginx:v2") },
+	}
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			start := time.Now()
+			err := call(context.Background())
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("got error %v, want context.DeadlineExceeded", err)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("call took %v despite a 100ms timeout", elapsed)
+			}
+		})
+	}
+}
+
+func TestZeroTimeoutKeepsCallerDeadline(t *testing.T) {
+	var requests atomic.Int32
+	m := newTestManager(t, stallingDaemon(&requests), 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := m.StartContainer(ctx, "web"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestListCreatedIsUTC(t *testing.T) {
+	// Run as if the agent's zone were east of UTC
+	local := time.Local
+	time.Local = time.FixedZone("UTC+5", 5*60*60)
+	defer func() { time.Local = local }()
+
+	const created = 1700000000 // 2023-11-14T22:13:20Z
+	id := strings.Repeat("a", 64)
+	m := newTestManager(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/json"):
+			fmt.Fprintf(w, `[{"Id":%q,"Names":["/web"],"Created":%d}]`, id, created)
+		case strings.HasSuffix(r.URL.Path, "/images/json"):
+			fmt.Fprintf(w, `[{"Id":"sha256:%s","Created":%d}]`, id, created)
+		default:
+			http.NotFound(w, r)
+		}
+	}), 0)
+
+	containers, err := m.ListContainers(context.Background(), ContainerFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	images, err := m.ListImages(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "2023-11-14T22:13:20Z"
+	if containers[0].Created != want {
+		t.Errorf("container Created = %q, want %q", containers[0].Created, want)
+	}
+	if images[0].Created != want {
+		t.Errorf("image Created = %q, want %q", images[0].Created, want)
+	}
+}
@@ -0,0 +1,103 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// ContainerEventActions are the container lifecycle events that can be streamed.
+var ContainerEventActions = []string{"create", "start", "restart", "die", "stop", "kill", "oom", "destroy"}
+
+// Event is a container lifecycle event.
+type Event struct {
+	Action      string `json:"action"`
+	ContainerID string `json:"containerId"`
+	Name        string `json:"name"`
+	Image       string `json:"image"`
+	// ExitCode is set on die events
+	ExitCode string `json:"exitCode,omitempty"`
+	Time     int64  `json:"time"` // unix milliseconds
+}
+
+// EventFilter narrows the events returned by StreamEvents.
+// Empty fields match everything.
+type EventFilter struct {
+	ContainerIDs []string
+	Actions      []string
+}
+
+// Validate checks that every action is a supported container event.
+func (f EventFilter) Validate() error {
+	for _, action := range f.Actions {
+		if !slices.Contains(ContainerEventActions, action) {
+			return fmt.Errorf("unsupported event %q: must be one of %s", action, strings.Join(ContainerEventActions, ", "))
+		}
+	}
+	return nil
+}
+
+// args converts the filter to Docker API filter arguments.
+func (f EventFilter) args() filters.Args {
+	args := filters.NewArgs(filters.Arg("type", "container"))
+	for _, id := range f.ContainerIDs {
+		args.Add("container", id)
+	}
+
+	actions := f.Actions
+	if len(actions) == 0 {
+		actions = ContainerEventActions
+	}
+	for _, action := range actions {
+		args.Add("event", action)
+	}
+	return args
+}
+
+// shortID truncates a container ID to the 12-character form used elsewhere.
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// StreamEvents sends container events matching filter to eventChan until ctx
+// is canceled or the daemon connection fails. It closes eventChan when done.
+func (m *Manager) StreamEvents(ctx context.Context, filter EventFilter, eventChan chan<- Event) error {
+	defer close(eventChan)
+
+	messages, errs := m.client.Events(ctx, types.EventsOptions{Filters: filter.args()})
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			return err
+		case msg := <-messages:
+			// Older daemons report some actions with a suffix, e.g. "exec_start: sh"
+			action, _, _ := strings.Cut(string(msg.Action), ":")
+
+			event := Event{
+				Action:      action,
+				ContainerID: shortID(msg.Actor.ID),
+				Name:        msg.Actor.Attributes["name"],
+				Image:       msg.Actor.Attributes["image"],
+				Time:        msg.TimeNano / 1e6,
+			}
+			if action == "die" {
+				event.ExitCode = msg.Actor.Attributes["exitCode"]
+			}
+
+			select {
+			case eventChan <- event:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
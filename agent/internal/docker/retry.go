@@ -0,0 +1,57 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+)
+
+// Read retry settings. Only idempotent reads are retried; actions such as
+// start and stop fail on the first error.
+const (
+	readAttempts    = 3
+	readBaseBackoff = 250 * time.Millisecond
+)
+
+// retryRead runs the read fn, bounding each attempt by the manager's
+// timeout, and retries it with exponential backoff while it fails with a
+// transient error such as the daemon restarting.
+func retryRead[T any](ctx context.Context, m *Manager, fn func(context.Context) (T, error)) (T, error) {
+	backoff := readBaseBackoff
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := m.withTimeout(ctx)
+		result, err := fn(attemptCtx)
+		cancel()
+		if err == nil || attempt == readAttempts || !isTransient(err) {
+			return result, err
+		}
+		slog.Debug("Docker request failed, retrying", "attempt", attempt, "backoff", backoff, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// isTransient reports whether err is a failure to reach the daemon that may
+// clear up on its own, e.g. while it restarts. Timeouts aren't retried, so a
+// hung daemon still fails within the configured timeout.
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return client.IsErrConnectionFailed(err) ||
+		errdefs.IsUnavailable(err) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET)
+}
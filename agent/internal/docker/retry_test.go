@@ -0,0 +1,82 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryReadDoesNotRetryTimeouts(t *testing.T) {
+	var requests atomic.Int32
+	m := newTestManager(t, stallingDaemon(&requests), 100*time.Millisecond)
+
+	reads := map[string]func(context.Context) error{
+		"list containers": func(ctx context.Context) error {
+			_, err := m.ListContainers(ctx, ContainerFilter{})
+			return err
+		},
+		"list images": func(ctx context.Context) error {
+			_, err := m.ListImages(ctx)
+			return err
+		},
+		"inspect": func(ctx context.Context) error {
+			_, err := m.GetContainerDetails(ctx, "web")
+			return err
+		},
+		"inspect raw": func(ctx context.Context) error {
+			_, err := m.InspectContainerRaw(ctx, "web")
+			return err
+		},
+	}
+	for name, read := range reads {
+		t.Run(name, func(t *testing.T) {
+			requests.Store(0)
+			if err := read(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("got error %v, want context.DeadlineExceeded", err)
+			}
+			if n := requests.Load(); n != 1 {
+				t.Errorf("daemon saw %d requests, want 1", n)
+			}
+		})
+	}
+}
+
+func TestRetryReadRetriesDroppedConnections(t *testing.T) {
+	var requests atomic.Int32
+	m := newTestManager(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Drop the connection like a restarting daemon, then recover
+		if requests.Add(1) < readAttempts {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}), time.Second)
+
+	if _, err := m.ListContainers(context.Background(), ContainerFilter{}); err != nil {
+		t.Fatalf("ListContainers: %v", err)
+	}
+	if n := requests.Load(); n != readAttempts {
+		t.Errorf("daemon saw %d requests, want %d", n, readAttempts)
+	}
+}
+
+func TestActionsDoNotRetry(t *testing.T) {
+	var requests atomic.Int32
+	m := newTestManager(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}), time.Second)
+
+	if err := m.StartContainer(context.Background(), "web"); err == nil {
+		t.Fatal("StartContainer succeeded against a dropped connection")
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("daemon saw %d requests, want 1", n)
+	}
+}
@@ -0,0 +1,128 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+)
+
+// Cgroup versions as reported in Info.CgroupVersion.
+const (
+	CgroupV1 = "1"
+	CgroupV2 = "2"
+)
+
+// ContainerStats is a point-in-time resource usage sample of a container.
+type ContainerStats struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+
+	// CPUPercent is computed against the previous sample of this container,
+	// where 100 is one full core, and is zero on the first one
+	CPUPercent float64 `json:"cpuPercent"`
+
+	// MemoryUsage excludes reclaimable page cache, matching docker stats
+	MemoryUsage   uint64  `json:"memoryUsage"`
+	MemoryLimit   uint64  `json:"memoryLimit"`
+	MemoryPercent float64 `json:"memoryPercent"`
+
+	// CgroupVersion is the layout the memory stats came from, "" if unknown
+	CgroupVersion string `json:"cgroupVersion,omitempty"`
+}
+
+// GetContainerStats samples a container's CPU and memory usage. The daemon
+// is asked for a single sample without waiting to prime CPU counters; CPU
+// usage is instead computed against the previous call for the container.
+func (m *Manager) GetContainerStats(ctx context.Context, containerID string) (*ContainerStats, error) {
+	stats, err := retryRead(ctx, m, func(ctx context.Context) (types.StatsJSON, error) {
+		var stats types.StatsJSON
+		resp, err := m.client.ContainerStatsOneShot(ctx, containerID)
+		if err != nil {
+			return stats, err
+		}
+		defer resp.Body.Close()
+		err = json.NewDecoder(resp.Body).Decode(&stats)
+		return stats, err
+	})
+	if err != nil {
+		if IsNotFound(err) {
+			m.statsMu.Lock()
+			delete(m.prevCPU, containerID)
+			m.statsMu.Unlock()
+		}
+		return nil, err
+	}
+
+	m.statsMu.Lock()
+	prev, ok := m.prevCPU[containerID]
+	if m.prevCPU == nil {
+		m.prevCPU = make(map[string]types.CPUStats)
+	}
+	m.prevCPU[containerID] = stats.CPUStats
+	m.statsMu.Unlock()
+
+	result := &ContainerStats{
+		ID:            shortID(stats.ID),
+		Name:          strings.TrimPrefix(stats.Name, "/"),
+		MemoryUsage:   MemoryUsage(stats.MemoryStats),
+		MemoryLimit:   stats.MemoryStats.Limit,
+		CgroupVersion: MemoryCgroupVersion(stats.MemoryStats),
+	}
+	if ok {
+		result.CPUPercent = cpuPercent(prev, stats.CPUStats)
+	}
+	if result.MemoryLimit > 0 {
+		result.MemoryPercent = float64(result.MemoryUsage) / float64(result.MemoryLimit) * 100
+	}
+	return result, nil
+}
+
+// cpuPercent returns the container's CPU usage between two samples as docker
+// stats computes it: its share of the host's CPU time, scaled by the number
+// of CPUs. Counters that went backwards, e.g. after a restart, give zero.
+func cpuPercent(prev, cur types.CPUStats) float64 {
+	if cur.CPUUsage.TotalUsage <= prev.CPUUsage.TotalUsage || cur.SystemUsage <= prev.SystemUsage {
+		return 0
+	}
+	cpus := float64(cur.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(cur.CPUUsage.PercpuUsage))
+	}
+	containerDelta := float64(cur.CPUUsage.TotalUsage - prev.CPUUsage.TotalUsage)
+	systemDelta := float64(cur.SystemUsage - prev.SystemUsage)
+	return containerDelta / systemDelta * cpus * 100
+}
+
+// MemoryCgroupVersion tells which cgroup layout produced mem: v1 reports
+// hierarchical totals such as total_inactive_file, v2 doesn't. It returns
+// "" for stats without a memory.stat breakdown, e.g. from Windows daemons.
+func MemoryCgroupVersion(mem types.MemoryStats) string {
+	if len(mem.Stats) == 0 {
+		return ""
+	}
+	if _, ok := mem.Stats["total_inactive_file"]; ok {
+		return CgroupV1
+	}
+	return CgroupV2
+}
+
+// MemoryUsage returns a container's memory usage the way docker stats
+// reports it: the cgroup's usage minus inactive page cache, which the kernel
+// can reclaim at any time. Raw usage overstates what the container needs,
+// the more so the more files it reads. The cache is total_inactive_file on
+// cgroup v1 and inactive_file on v2.
+func MemoryUsage(mem types.MemoryStats) uint64 {
+	var inactive uint64
+	switch MemoryCgroupVersion(mem) {
+	case CgroupV1:
+		inactive = mem.Stats["total_inactive_file"]
+	case CgroupV2:
+		inactive = mem.Stats["inactive_file"]
+	}
+	if inactive >= mem.Usage {
+		return mem.Usage
+	}
+	return mem.Usage - inactive
+}
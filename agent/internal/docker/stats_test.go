@@ -0,0 +1,106 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestMemoryUsage(t *testing.T) {
+	tests := []struct {
+		name        string
+		mem         types.MemoryStats
+		wantVersion string
+		wantUsage   uint64
+	}{
+		{
+			name: "cgroup v1 subtracts total_inactive_file",
+			mem: types.MemoryStats{Usage: 500 << 20, Stats: map[string]uint64{
+				"cache":               200 << 20,
+				"inactive_file":       50 << 20,
+				"total_inactive_file": 120 << 20,
+				"total_rss":           300 << 20,
+			}},
+			wantVersion: CgroupV1,
+			wantUsage:   380 << 20,
+		},
+		{
+			name: "cgroup v2 subtracts inactive_file",
+			mem: types.MemoryStats{Usage: 500 << 20, Stats: map[string]uint64{
+				"anon":          300 << 20,
+				"file":          180 << 20,
+				"active_file":   60 << 20,
+				"inactive_file": 120 << 20,
+			}},
+			wantVersion: CgroupV2,
+			wantUsage:   380 << 20,
+		},
+		{
+			name:        "cache not below usage leaves usage alone",
+			mem:         types.MemoryStats{Usage: 100, Stats: map[string]uint64{"inactive_file": 100}},
+			wantVersion: CgroupV2,
+			wantUsage:   100,
+		},
+		{
+			name:      "no breakdown reports raw usage",
+			mem:       types.MemoryStats{Usage: 100},
+			wantUsage: 100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MemoryCgroupVersion(tt.mem); got != tt.wantVersion {
+				t.Errorf("MemoryCgroupVersion() = %q, want %q", got, tt.wantVersion)
+			}
+			if got := MemoryUsage(tt.mem); got != tt.wantUsage {
+				t.Errorf("MemoryUsage() = %d, want %d", got, tt.wantUsage)
+			}
+		})
+	}
+}
+
+func TestGetContainerStats(t *testing.T) {
+	var samples atomic.Int32
+	m := newTestManager(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Each sample the container uses half of one of the host's 4 CPUs
+		n := uint64(samples.Add(1))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{
+			"id": "aaaaaaaaaaaa1111111111111111111111111111111111111111111111111111",
+			"name": "/web",
+			"cpu_stats": {"cpu_usage": {"total_usage": %d}, "system_cpu_usage": %d, "online_cpus": 4},
+			"memory_stats": {"usage": 524288000, "limit": 1048576000, "stats": {"inactive_file": 125829120}}
+		}`, n*500_000_000, n*4_000_000_000)
+	}), time.Second)
+
+	first, err := m.GetContainerStats(context.Background(), "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID != "aaaaaaaaaaaa" || first.Name != "web" {
+		t.Errorf("got ID %q and name %q, want aaaaaaaaaaaa and web", first.ID, first.Name)
+	}
+	if first.CPUPercent != 0 {
+		t.Errorf("first sample CPUPercent = %v, want 0", first.CPUPercent)
+	}
+	if first.MemoryUsage != 380<<20 || first.CgroupVersion != CgroupV2 {
+		t.Errorf("got memory %d from cgroup v%s, want %d from v2", first.MemoryUsage, first.CgroupVersion, 380<<20)
+	}
+	if math.Abs(first.MemoryPercent-38) > 0.01 {
+		t.Errorf("MemoryPercent = %v, want 38", first.MemoryPercent)
+	}
+
+	second, err := m.GetContainerStats(context.Background(), "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(second.CPUPercent-50) > 0.01 {
+		t.Errorf("CPUPercent = %v, want 50", second.CPUPercent)
+	}
+}
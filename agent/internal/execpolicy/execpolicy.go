@@ -0,0 +1,191 @@
+// Package execpolicy decides which commands the agent is allowed to execute.
+package execpolicy
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+var (
+	// ErrExecDisabled is returned when command execution is disabled entirely.
+	ErrExecDisabled = errors.New("command execution is disabled")
+
+	// ErrCommandNotAllowed is returned when a command does not match the allowlist.
+	ErrCommandNotAllowed = errors.New("command not permitted by exec allowlist")
+
+	// ErrInvalidDir is returned for a working directory that isn't an existing absolute directory.
+	ErrInvalidDir = errors.New("working directory must be an existing absolute directory")
+
+	// ErrDirNotAllowed is returned for a working directory outside the permitted directories.
+	ErrDirNotAllowed = errors.New("working directory not permitted")
+
+	// ErrEnvNotAllowed is returned for an environment variable that would let
+	// an allowlisted command run something else.
+	ErrEnvNotAllowed = errors.New("environment variable not permitted by exec allowlist")
+)
+
+// shellMetacharacters are rejected in prefix-matched commands so that an
+// allowed prefix cannot be chained with arbitrary shell input, expanded to
+// other paths by globbing or ~, or have its words regrouped by quoting.
+const shellMetacharacters = ";&|`$<>()\\\n\r*?[]~'\""
+
+// protectedEnv are environment variables that change which program a shell
+// command runs or what the shell executes before it.
+var protectedEnv = map[string]bool{
+	"PATH":       true,
+	"IFS":        true,
+	"ENV":        true,
+	"BASH_ENV":   true,
+	"SHELLOPTS":  true,
+	"BASHOPTS":   true,
+	"PS4":        true,
+	"CDPATH":     true,
+	"GCONV_PATH": true,
+}
+
+// protectedEnvPrefixes are prefixes of dynamic loader variables, which can
+// inject a library into any program.
+var protectedEnvPrefixes = []string{"LD_", "DYLD_"}
+
+// Policy holds the exec restrictions.
+// The zero value allows every command.
+type Policy struct {
+	disabled bool
+	exact    map[string]bool
+	prefixes []string
+	dirs     []string
+}
+
+// New creates a policy. If allowlistPath is empty, all commands are permitted
+// unless disabled is set. Commands may run in dirs or their subdirectories;
+// if dirs is empty any directory is permitted.
+//
+// The allowlist file contains one entry per line. Blank lines and lines
+// starting with '#' are ignored. An entry ending in '*' permits any command
+// starting with the text before the '*', so "systemctl status *" permits
+// every further argument to systemctl status; such commands may not contain
+// shell metacharacters or ".." path segments. Any other entry must match
+// exactly.
+func New(disabled bool, allowlistPath string, dirs []string) (*Policy, error) {
+	p := &Policy{disabled: disabled}
+	for _, dir := range dirs {
+		// Compare against the real path, as CheckDir does
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			dir = resolved
+		}
+		p.dirs = append(p.dirs, filepath.Clean(dir))
+	}
+	if allowlistPath == "" {
+		return p, nil
+	}
+
+	f, err := os.Open(allowlistPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open exec allowlist: %w", err)
+	}
+	defer f.Close()
+
+	p.exact = make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if prefix, ok := strings.CutSuffix(line, "*"); ok {
+			p.prefixes = append(p.prefixes, prefix)
+			continue
+		}
+		p.exact[line] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read exec allowlist: %w", err)
+	}
+
+	return p, nil
+}
+
+// Check returns nil if the command may be executed.
+func (p *Policy) Check(command string) error {
+	if p.disabled {
+		return ErrExecDisabled
+	}
+	if p.exact == nil {
+		return nil
+	}
+
+	command = strings.TrimSpace(command)
+	if p.exact[command] {
+		return nil
+	}
+	if strings.ContainsAny(command, shellMetacharacters) || hasParentSegment(command) {
+		return ErrCommandNotAllowed
+	}
+	for _, prefix := range p.prefixes {
+		if strings.HasPrefix(command, prefix) {
+			return nil
+		}
+	}
+	return ErrCommandNotAllowed
+}
+
+// hasParentSegment reports whether command has a ".." path segment, which
+// could step out of a directory an allowed prefix names. Segments end at
+// slashes, whitespace and the '=' of options such as --file=../x.
+func hasParentSegment(command string) bool {
+	segments := strings.FieldsFunc(command, func(r rune) bool {
+		return r == '/' || r == '=' || unicode.IsSpace(r)
+	})
+	return slices.Contains(segments, "..")
+}
+
+// CheckEnv returns nil if the command may run with the environment variable
+// name set. While an allowlist is active, variables that could make an
+// allowed command run something else, such as PATH or LD_PRELOAD, are
+// rejected.
+func (p *Policy) CheckEnv(name string) error {
+	if p.exact == nil {
+		return nil
+	}
+	if protectedEnv[name] {
+		return fmt.Errorf("%w: %s", ErrEnvNotAllowed, name)
+	}
+	for _, prefix := range protectedEnvPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return fmt.Errorf("%w: %s", ErrEnvNotAllowed, name)
+		}
+	}
+	return nil
+}
+
+// CheckDir returns nil if commands may run in dir, which must be an existing
+// absolute directory. Symlinks are resolved before comparing against the
+// permitted directories.
+func (p *Policy) CheckDir(dir string) error {
+	if !filepath.IsAbs(dir) {
+		return ErrInvalidDir
+	}
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return ErrInvalidDir
+	}
+	if info, err := os.Stat(resolved); err != nil || !info.IsDir() {
+		return ErrInvalidDir
+	}
+	if len(p.dirs) == 0 {
+		return nil
+	}
+
+	for _, allowed := range p.dirs {
+		if rel, err := filepath.Rel(allowed, resolved); err == nil && filepath.IsLocal(rel) {
+			return nil
+		}
+	}
+	return ErrDirNotAllowed
+}
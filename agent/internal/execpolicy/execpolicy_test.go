@@ -0,0 +1,74 @@
+package execpolicy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestPolicy creates a policy from allowlist entries.
+func newTestPolicy(t *testing.T, entries string) *Policy {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "allowlist")
+	if err := os.WriteFile(path, []byte(entries), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := New(false, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestCheckPrefix(t *testing.T) {
+	p := newTestPolicy(t, "uptime\nsystemctl status *\ncat /var/log/*\n")
+
+	for _, tc := range []struct {
+		command string
+		allowed bool
+	}{
+		{"uptime", true},
+		{"systemctl status nginx", true},
+		// A prefix permits any further arguments
+		{"systemctl status nginx --no-pager -n 50", true},
+		{"cat /var/log/syslog", true},
+		{"cat /var/log/nginx/access.log", true},
+		{"systemctl restart nginx", false},
+		{"systemctl status nginx; reboot", false},
+		{"systemctl status $(reboot)", false},
+		{"cat /var/log/../../etc/shadow", false},
+		{"cat /var/log/syslog ../../etc/shadow", false},
+		{"cat /var/log/syslog --file=../etc/shadow", false},
+		{"cat /var/log/*", false},
+		{"cat /var/log/sys?og", false},
+		{"cat /var/log/[s]yslog", false},
+		{"cat /var/log/syslog ~root/.ssh/id_rsa", false},
+		{"cat /var/log/syslog '/etc/shadow'", false},
+		{`cat /var/log/syslog "/etc/shadow"`, false},
+		// ".." inside a name isn't a parent directory
+		{"cat /var/log/app..1.log", true},
+	} {
+		err := p.Check(tc.command)
+		if tc.allowed && err != nil {
+			t.Errorf("Check(%q) = %v, want allowed", tc.command, err)
+		}
+		if !tc.allowed && !errors.Is(err, ErrCommandNotAllowed) {
+			t.Errorf("Check(%q) = %v, want ErrCommandNotAllowed", tc.command, err)
+		}
+	}
+}
+
+func TestCheckEnv(t *testing.T) {
+	for _, name := range []string{"PATH", "LD_PRELOAD", "LD_LIBRARY_PATH", "DYLD_INSERT_LIBRARIES", "IFS", "ENV", "BASH_ENV", "SHELLOPTS", "PS4"} {
+		if err := (&Policy{}).CheckEnv(name); err != nil {
+			t.Errorf("without an allowlist, CheckEnv(%q) = %v, want nil", name, err)
+		}
+		if err := newTestPolicy(t, "uptime\n").CheckEnv(name); !errors.Is(err, ErrEnvNotAllowed) {
+			t.Errorf("CheckEnv(%q) = %v, want ErrEnvNotAllowed", name, err)
+		}
+	}
+	if err := newTestPolicy(t, "uptime\n").CheckEnv("GREETING"); err != nil {
+		t.Errorf("CheckEnv(GREETING) = %v, want nil", err)
+	}
+}
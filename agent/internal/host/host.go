@@ -0,0 +1,91 @@
+// Package host schedules reboots and shutdowns of the machine.
+package host
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Power actions.
+const (
+	ActionReboot   = "reboot"
+	ActionShutdown = "shutdown"
+)
+
+// commandTimeout bounds a shutdown command, which only schedules the action.
+const commandTimeout = 30 * time.Second
+
+// ErrInvalidAction is returned for actions other than reboot and shutdown.
+var ErrInvalidAction = errors.New("action must be reboot or shutdown")
+
+// Schedule is a scheduled reboot or shutdown.
+type Schedule struct {
+	Action      string    `json:"action"`
+	ScheduledAt time.Time `json:"scheduledAt"`
+}
+
+// Command returns the shutdown command line that performs action after
+// delay. shutdown only takes whole minutes, so delay is rounded up.
+func Command(action string, delay time.Duration) ([]string, error) {
+	var flag string
+	switch action {
+	case ActionReboot:
+		flag = "-r"
+	case ActionShutdown:
+		flag = "-h"
+	default:
+		return nil, ErrInvalidAction
+	}
+
+	when := "now"
+	if minutes := delayMinutes(delay); minutes > 0 {
+		when = "+" + strconv.Itoa(minutes)
+	}
+	return []string{"shutdown", flag, when}, nil
+}
+
+// ScheduleAction schedules action after delay, replacing any pending one.
+func ScheduleAction(ctx context.Context, action string, delay time.Duration) (*Schedule, error) {
+	args, err := Command(action, delay)
+	if err != nil {
+		return nil, err
+	}
+	at := time.Now().Add(time.Duration(delayMinutes(delay)) * time.Minute).Truncate(time.Second)
+	if err := run(ctx, args); err != nil {
+		return nil, err
+	}
+	return &Schedule{Action: action, ScheduledAt: at}, nil
+}
+
+// Cancel aborts a scheduled reboot or shutdown.
+func Cancel(ctx context.Context) error {
+	return run(ctx, CancelCommand())
+}
+
+// CancelCommand returns the command line that aborts a scheduled action.
+func CancelCommand() []string {
+	return []string{"shutdown", "-c"}
+}
+
+func delayMinutes(delay time.Duration) int {
+	return int((delay + time.Minute - 1) / time.Minute)
+}
+
+func run(ctx context.Context, args []string) error {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s: %s", args[0], msg)
+		}
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+// Package logging configures the agent's leveled logger.
+package logging
+
+import (
+	"log/slog"
+	"os"
+)
+
+// level is shared by the installed handler so it can be changed at runtime.
+var level = new(slog.LevelVar)
+
+// ParseLevel parses a level name (debug, info, warn, error).
+func ParseLevel(name string) (slog.Level, error) {
+	var l slog.Level
+	err := l.UnmarshalText([]byte(name))
+	return l, err
+}
+
+// Log output formats.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Setup installs a timestamped stdout logger as the slog default.
+// format selects human-readable text or one JSON object per line.
+// Output from the standard log package is routed through it at info level.
+func Setup(lvl slog.Level, format string) {
+	level.Set(lvl)
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	if format == FormatJSON {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+}
+
+// SetLevel changes the minimum level of the installed logger.
+func SetLevel(lvl slog.Level) {
+	level.Set(lvl)
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// CachedMetrics returns the last full snapshot if it was sampled less than
+// maxAge ago, otherwise it collects a new one. Concurrent callers that miss
+// the cache share a single collection instead of each blocking on CPU
+// sampling. The snapshot's Timestamp tells callers how stale it is; the
+// result is a copy the caller may modify.
+func (c *Collector) CachedMetrics(maxAge time.Duration) (*Metrics, error) {
+	if m := c.cachedSnapshot(maxAge); m != nil {
+		return m, nil
+	}
+
+	c.snapshotRefreshMu.Lock()
+	defer c.snapshotRefreshMu.Unlock()
+	// Another caller may have collected while we waited
+	if m := c.cachedSnapshot(maxAge); m != nil {
+		return m, nil
+	}
+
+	m, err := c.GetMetrics()
+	if err != nil {
+		return nil, err
+	}
+
+	c.snapshotMu.Lock()
+	c.snapshot = m.Clone()
+	c.snapshotMu.Unlock()
+	return m, nil
+}
+
+// cachedSnapshot returns a copy of the cached snapshot, or nil if there is
+// none younger than maxAge.
+func (c *Collector) cachedSnapshot(maxAge time.Duration) *Metrics {
+	c.snapshotMu.Lock()
+	defer c.snapshotMu.Unlock()
+	if c.snapshot == nil || time.Since(time.UnixMilli(c.snapshot.Timestamp)) >= maxAge {
+		return nil
+	}
+	return c.snapshot.Clone()
+}
+
+// Clone returns a deep copy of m, so that one copy can be humanized or
+// otherwise modified without affecting the other.
+func (m *Metrics) Clone() *Metrics {
+	out := *m
+	if m.CPU != nil {
+		cpu := *m.CPU
+		out.CPU = &cpu
+	}
+	if m.Memory != nil {
+		mem := *m.Memory
+		out.Memory = &mem
+	}
+	if m.Disk != nil {
+		disk := *m.Disk
+		disk.IO = slices.Clone(m.Disk.IO)
+		disk.Partitions = slices.Clone(m.Disk.Partitions)
+		out.Disk = &disk
+	}
+	if m.Network != nil {
+		network := *m.Network
+		out.Network = &network
+	}
+	out.GPU = slices.Clone(m.GPU)
+	out.Errors = maps.Clone(m.Errors)
+	return &out
+}
@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"context"
+	"maps"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	psnet "github.com/shirou/gopsutil/v4/net"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// ConnectionStates are the socket states GetConnections can filter on.
+var ConnectionStates = []string{
+	"ESTABLISHED", "SYN_SENT", "SYN_RECV", "FIN_WAIT1", "FIN_WAIT2", "TIME_WAIT",
+	"CLOSE", "CLOSE_WAIT", "LAST_ACK", "LISTEN", "CLOSING", "NONE",
+}
+
+// Connection is an open TCP or UDP socket.
+type Connection struct {
+	Protocol      string `json:"protocol"` // tcp, tcp6, udp or udp6
+	LocalAddress  string `json:"localAddress"`
+	RemoteAddress string `json:"remoteAddress,omitempty"`
+	State         string `json:"state"` // e.g. LISTEN, ESTABLISHED; NONE for UDP
+	PID           int32  `json:"pid,omitempty"`
+	Process       string `json:"process,omitempty"`
+}
+
+// GetConnections returns open sockets, keeping only those in state when it
+// is non-empty (case-insensitive, e.g. "listen"). Sockets owned by other users
+// are reported without a PID unless the agent runs with enough privileges.
+func (c *Collector) GetConnections(ctx context.Context, state string) ([]Connection, error) {
+	stats, err := psnet.ConnectionsWithContext(ctx, "inet")
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[int32]string)
+	conns := []Connection{}
+	for _, st := range stats {
+		if state != "" && !strings.EqualFold(st.Status, state) {
+			continue
+		}
+
+		conn := Connection{
+			Protocol:     connProtocol(st),
+			LocalAddress: formatAddr(st.Laddr),
+			State:        st.Status,
+			PID:          st.Pid,
+		}
+		if st.Raddr.Port != 0 {
+			conn.RemoteAddress = formatAddr(st.Raddr)
+		}
+		if st.Pid > 0 {
+			name, ok := names[st.Pid]
+			if !ok {
+				name = processName(ctx, st.Pid)
+				names[st.Pid] = name
+			}
+			conn.Process = name
+		}
+		conns = append(conns, conn)
+	}
+	return conns, nil
+}
+
+// TCPSummary counts TCP sockets by state. Every state is present, so
+// TIME_WAIT or CLOSE_WAIT build-ups can be read without checking for keys.
+type TCPSummary struct {
+	Total     int            `json:"total"`
+	States    map[string]int `json:"states"`
+	Timestamp int64          `json:"timestamp"` // when sockets were counted, unix milliseconds
+}
+
+// tcpSummaryTTL is how long a TCP summary is reused; listing sockets walks
+// every process's file descriptors.
+const tcpSummaryTTL = 5 * time.Second
+
+// GetTCPSummary returns TCP socket counts by state, reusing the last count
+// for tcpSummaryTTL.
+func (c *Collector) GetTCPSummary(ctx context.Context) (*TCPSummary, error) {
+	c.tcpSummaryMu.Lock()
+	defer c.tcpSummaryMu.Unlock()
+
+	if s := c.tcpSummary; s != nil && time.Since(time.UnixMilli(s.Timestamp)) < tcpSummaryTTL {
+		return s.clone(), nil
+	}
+
+	stats, err := psnet.ConnectionsWithContext(ctx, "tcp")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &TCPSummary{Total: len(stats), States: make(map[string]int)}
+	for _, state := range ConnectionStates {
+		if state != "NONE" {
+			s.States[state] = 0
+		}
+	}
+	for _, st := range stats {
+		s.States[st.Status]++
+	}
+	s.Timestamp = time.Now().UnixMilli()
+
+	c.tcpSummary = s
+	return s.clone(), nil
+}
+
+func (s *TCPSummary) clone() *TCPSummary {
+	out := *s
+	out.States = maps.Clone(s.States)
+	return &out
+}
+
+// connProtocol names a socket's protocol from its type and address family.
+func connProtocol(st psnet.ConnectionStat) string {
+	proto := "tcp"
+	if st.Type == syscall.SOCK_DGRAM {
+		proto = "udp"
+	}
+	if st.Family == syscall.AF_INET6 {
+		proto += "6"
+	}
+	return proto
+}
+
+func formatAddr(a psnet.Addr) string {
+	return net.JoinHostPort(a.IP, strconv.FormatUint(uint64(a.Port), 10))
+}
+
+// processName returns the name of pid, or "" if it can't be read.
+func processName(ctx context.Context, pid int32) string {
+	p, err := process.NewProcessWithContext(ctx, pid)
+	if err != nil {
+		return ""
+	}
+	name, _ := p.NameWithContext(ctx)
+	return name
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"context"
+	"encoding/csv"
+	"log/slog"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GPUMetrics contains usage information for a single GPU.
+type GPUMetrics struct {
+	Index              int     `json:"index"`
+	Name               string  `json:"name"`
+	UtilizationPercent float64 `json:"utilizationPercent"`
+	MemoryUsed         uint64  `json:"memoryUsed"`
+	MemoryTotal        uint64  `json:"memoryTotal"`
+	Temperature        float64 `json:"temperature"` // degrees Celsius
+
+	// Formatted memory sizes, set by Humanize
+	MemoryUsedHuman  string `json:"memoryUsedHuman,omitempty"`
+	MemoryTotalHuman string `json:"memoryTotalHuman,omitempty"`
+}
+
+// nvidiaSMIQuery lists the fields requested from nvidia-smi, in column order.
+const nvidiaSMIQuery = "index,name,utilization.gpu,memory.used,memory.total,temperature.gpu"
+
+// getGPUMetrics queries nvidia-smi for per-GPU stats.
+// It returns nil when nvidia-smi is unavailable or fails.
+func (c *Collector) getGPUMetrics() []GPUMetrics {
+	if c.nvidiaSMI == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, c.nvidiaSMI,
+		"--query-gpu="+nvidiaSMIQuery, "--format=csv,noheader,nounits").Output()
+	if err != nil {
+		slog.Debug("nvidia-smi query failed", "error", err)
+		return nil
+	}
+
+	return parseNvidiaSMIOutput(string(out))
+}
+
+// parseNvidiaSMIOutput parses CSV rows of nvidiaSMIQuery fields.
+// Memory is reported by nvidia-smi in MiB and converted to bytes.
+// Unsupported values (e.g. "[N/A]") are reported as zero.
+func parseNvidiaSMIOutput(output string) []GPUMetrics {
+	reader := csv.NewReader(strings.NewReader(output))
+	reader.TrimLeadingSpace = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		slog.Debug("Failed to parse nvidia-smi output", "error", err)
+		return nil
+	}
+
+	gpus := make([]GPUMetrics, 0, len(rows))
+	for _, row := range rows {
+		if len(row) < 6 {
+			continue
+		}
+		index, _ := strconv.Atoi(strings.TrimSpace(row[0]))
+		gpus = append(gpus, GPUMetrics{
+			Index:              index,
+			Name:               strings.TrimSpace(row[1]),
+			UtilizationPercent: parseSMIFloat(row[2]),
+			MemoryUsed:         uint64(parseSMIFloat(row[3])) << 20,
+			MemoryTotal:        uint64(parseSMIFloat(row[4])) << 20,
+			Temperature:        parseSMIFloat(row[5]),
+		})
+	}
+	return gpus
+}
+
+// parseSMIFloat parses a numeric nvidia-smi field, returning 0 if unsupported.
+func parseSMIFloat(field string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// HistorySample is a compact point-in-time summary stored in History.
+type HistorySample struct {
+	Timestamp     int64   `json:"timestamp"` // unix milliseconds
+	CPUPercent    float64 `json:"cpuPercent"`
+	MemoryPercent float64 `json:"memoryPercent"`
+	DiskPercent   float64 `json:"diskPercent"`
+
+	// Network rates are computed against the previous sample and are zero on the first one
+	NetRecvBytesPerSec float64 `json:"netRecvBytesPerSec"`
+	NetSentBytesPerSec float64 `json:"netSentBytesPerSec"`
+}
+
+// History is a fixed-size ring buffer of metric samples, safe for concurrent use.
+type History struct {
+	mu      sync.RWMutex
+	samples []HistorySample
+	next    int  // index the next sample is written to
+	full    bool // whether the buffer has wrapped
+
+	// prevNet is the previous network sample, used to compute rates
+	prevNet     NetworkMetrics
+	prevNetTime int64
+}
+
+// NewHistory creates a history holding at most capacity samples.
+func NewHistory(capacity int) *History {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &History{samples: make([]HistorySample, capacity)}
+}
+
+// Add records m, overwriting the oldest sample once the buffer is full.
+func (h *History) Add(m *Metrics) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Disabled categories are recorded as zero
+	sample := HistorySample{Timestamp: m.Timestamp}
+	if m.CPU != nil {
+		sample.CPUPercent = m.CPU.UsagePercent
+	}
+	if m.Memory != nil {
+		sample.MemoryPercent = m.Memory.UsagePercent
+	}
+	if m.Disk != nil {
+		sample.DiskPercent = m.Disk.UsagePercent
+	}
+	if m.Network != nil {
+		if h.prevNetTime > 0 && m.Timestamp > h.prevNetTime {
+			elapsed := float64(m.Timestamp-h.prevNetTime) / 1000
+			sample.NetRecvBytesPerSec = rate(h.prevNet.BytesRecv, m.Network.BytesRecv, elapsed)
+			sample.NetSentBytesPerSec = rate(h.prevNet.BytesSent, m.Network.BytesSent, elapsed)
+		}
+		h.prevNet = *m.Network
+		h.prevNetTime = m.Timestamp
+	}
+
+	h.samples[h.next] = sample
+	h.next = (h.next + 1) % len(h.samples)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// Since returns samples taken at or after since, oldest first. A positive
+// resolution averages the samples into buckets of that width.
+func (h *History) Since(since time.Time, resolution time.Duration) []HistorySample {
+	h.mu.RLock()
+	var ordered []HistorySample
+	if h.full {
+		ordered = append(ordered, h.samples[h.next:]...)
+	}
+	ordered = append(ordered, h.samples[:h.next]...)
+	h.mu.RUnlock()
+
+	sinceMs := since.UnixMilli()
+	result := make([]HistorySample, 0, len(ordered))
+	for _, s := range ordered {
+		if s.Timestamp >= sinceMs {
+			result = append(result, s)
+		}
+	}
+
+	if resolution <= 0 {
+		return result
+	}
+	return downsample(result, resolution.Milliseconds())
+}
+
+// downsample averages chronologically ordered samples into buckets of
+// bucketMs milliseconds, each stamped with the start of its bucket.
+func downsample(samples []HistorySample, bucketMs int64) []HistorySample {
+	if bucketMs <= 0 {
+		return samples
+	}
+
+	var result []HistorySample
+	var sum HistorySample
+	count := 0
+	flush := func() {
+		if count == 0 {
+			return
+		}
+		n := float64(count)
+		result = append(result, HistorySample{
+			Timestamp:          sum.Timestamp,
+			CPUPercent:         sum.CPUPercent / n,
+			MemoryPercent:      sum.MemoryPercent / n,
+			DiskPercent:        sum.DiskPercent / n,
+			NetRecvBytesPerSec: sum.NetRecvBytesPerSec / n,
+			NetSentBytesPerSec: sum.NetSentBytesPerSec / n,
+		})
+		sum, count = HistorySample{}, 0
+	}
+
+	for _, s := range samples {
+		bucket := s.Timestamp - s.Timestamp%bucketMs
+		if count > 0 && bucket != sum.Timestamp {
+			flush()
+		}
+		sum.Timestamp = bucket
+		sum.CPUPercent += s.CPUPercent
+		sum.MemoryPercent += s.MemoryPercent
+		sum.DiskPercent += s.DiskPercent
+		sum.NetRecvBytesPerSec += s.NetRecvBytesPerSec
+		sum.NetSentBytesPerSec += s.NetSentBytesPerSec
+		count++
+	}
+	flush()
+
+	return result
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+)
+
+// FormatBytes renders n in binary units with one decimal, e.g. "15.3 GiB".
+func FormatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit && exp < 5; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatRate renders a bytes-per-second rate, e.g. "1.2 MiB/s".
+func formatRate(bytesPerSec float64) string {
+	return FormatBytes(uint64(math.Max(bytesPerSec, 0))) + "/s"
+}
+
+// roundPercent rounds a percentage to one decimal place.
+func roundPercent(p float64) float64 {
+	return math.Round(p*10) / 10
+}
+
+// Humanize fills the *Human fields of every category and rounds percentages
+// to one decimal place.
+func (m *Metrics) Humanize() {
+	if m.CPU != nil {
+		m.CPU.Humanize()
+	}
+	if m.Memory != nil {
+		m.Memory.Humanize()
+	}
+	if m.Disk != nil {
+		m.Disk.Humanize()
+	}
+	if m.Network != nil {
+		m.Network.Humanize()
+	}
+	for i := range m.GPU {
+		m.GPU[i].Humanize()
+	}
+}
+
+// Humanize rounds the usage percentage.
+func (m *CPUMetrics) Humanize() {
+	m.UsagePercent = roundPercent(m.UsagePercent)
+}
+
+// Humanize fills the *Human fields and rounds the usage percentage.
+func (m *MemoryMetrics) Humanize() {
+	m.TotalHuman = FormatBytes(m.Total)
+	m.UsedHuman = FormatBytes(m.Used)
+	m.FreeHuman = FormatBytes(m.Free)
+	m.UsagePercent = roundPercent(m.UsagePercent)
+}
+
+// Humanize fills the *Human fields and rounds the usage percentages.
+func (m *DiskMetrics) Humanize() {
+	m.TotalHuman = FormatBytes(m.Total)
+	m.UsedHuman = FormatBytes(m.Used)
+	m.FreeHuman = FormatBytes(m.Free)
+	m.UsagePercent = roundPercent(m.UsagePercent)
+	m.InodesUsagePercent = roundPercent(m.InodesUsagePercent)
+	for i := range m.IO {
+		io := &m.IO[i]
+		io.ReadRateHuman = formatRate(io.ReadBytesPerSec)
+		io.WriteRateHuman = formatRate(io.WriteBytesPerSec)
+	}
+	for i := range m.Partitions {
+		p := &m.Partitions[i]
+		p.TotalHuman = FormatBytes(p.Total)
+		p.UsedHuman = FormatBytes(p.Used)
+		p.FreeHuman = FormatBytes(p.Free)
+		p.UsagePercent = roundPercent(p.UsagePercent)
+		p.InodesUsagePercent = roundPercent(p.InodesUsagePercent)
+	}
+}
+
+// Humanize fills the *Human fields.
+func (m *NetworkMetrics) Humanize() {
+	m.BytesRecvHuman = FormatBytes(m.BytesRecv)
+	m.BytesSentHuman = FormatBytes(m.BytesSent)
+}
+
+// Humanize fills the *Human fields.
+func (m *InterfaceMetrics) Humanize() {
+	m.BytesRecvHuman = FormatBytes(m.BytesRecv)
+	m.BytesSentHuman = FormatBytes(m.BytesSent)
+	m.RecvRateHuman = formatRate(m.RecvBytesPerSec)
+	m.SentRateHuman = formatRate(m.SentBytesPerSec)
+}
+
+// Humanize fills the *Human fields and rounds the utilization percentage.
+func (m *GPUMetrics) Humanize() {
+	m.MemoryUsedHuman = FormatBytes(m.MemoryUsed)
+	m.MemoryTotalHuman = FormatBytes(m.MemoryTotal)
+	m.UtilizationPercent = roundPercent(m.UtilizationPercent)
+}
@@ -2,6 +2,12 @@
 package metrics
 
 import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
 	"time"
 
 	"github.com/shirou/gopsutil/v4/cpu"
@@ -11,15 +17,30 @@ import (
 	"github.com/shirou/gopsutil/v4/net"
 )
 
-// Metrics contains all system metrics.
+// Metrics contains all system metrics. Disabled or failed categories are nil.
 type Metrics struct {
-	CPU       CPUMetrics     `json:"cpu"`
-	Memory    MemoryMetrics  `json:"memory"`
-	Disk      DiskMetrics    `json:"disk"`
-	Network   NetworkMetrics `json:"network"`
-	Timestamp int64          `json:"timestamp"`
+	CPU       *CPUMetrics     `json:"cpu,omitempty"`
+	Memory    *MemoryMetrics  `json:"memory,omitempty"`
+	Disk      *DiskMetrics    `json:"disk,omitempty"`
+	Network   *NetworkMetrics `json:"network,omitempty"`
+	GPU       []GPUMetrics    `json:"gpu,omitempty"`
+	Timestamp int64           `json:"timestamp"`
+
+	// Instance names the agent that collected the metrics, set by the server
+	Instance string `json:"instance,omitempty"`
+
+	// Errors maps each category that failed to collect to its error
+	Errors map[string]string `json:"errors,omitempty"`
 }
 
+// Metric categories that can be disabled in Options.
+const (
+	CategoryCPU     = "cpu"
+	CategoryMemory  = "memory"
+	CategoryDisk    = "disk"
+	CategoryNetwork = "network"
+)
+
 // CPUMetrics contains CPU usage information.
 type CPUMetrics struct {
 	UsagePercent float64 `json:"usagePercent"`
@@ -33,6 +54,11 @@ type MemoryMetrics struct {
 	Used         uint64  `json:"used"`
 	Free         uint64  `json:"free"`
 	UsagePercent float64 `json:"usagePercent"`
+
+	// Formatted sizes, set by Humanize
+	TotalHuman string `json:"totalHuman,omitempty"`
+	UsedHuman  string `json:"usedHuman,omitempty"`
+	FreeHuman  string `json:"freeHuman,omitempty"`
 }
 
 // DiskMetrics contains disk usage information.
@@ -42,6 +68,23 @@ type DiskMetrics struct {
 	Free         uint64  `json:"free"`
 	UsagePercent float64 `json:"usagePercent"`
 	MountPoint   string  `json:"mountPoint"`
+
+	// Formatted sizes, set by Humanize
+	TotalHuman string `json:"totalHuman,omitempty"`
+	UsedHuman  string `json:"usedHuman,omitempty"`
+	FreeHuman  string `json:"freeHuman,omitempty"`
+
+	// Inode usage; zero on platforms without inodes
+	InodesTotal        uint64  `json:"inodesTotal"`
+	InodesUsed         uint64  `json:"inodesUsed"`
+	InodesFree         uint64  `json:"inodesFree"`
+	InodesUsagePercent float64 `json:"inodesUsagePercent"`
+
+	// IO holds I/O counters and rates per block device, not per mount point
+	IO []DiskIOMetrics `json:"io,omitempty"`
+
+	// Partitions holds usage for every mounted filesystem that passes the mount filter
+	Partitions []PartitionMetrics `json:"partitions,omitempty"`
 }
 
 // NetworkMetrics contains network I/O information.
@@ -50,6 +93,10 @@ type NetworkMetrics struct {
 	BytesSent   uint64 `json:"bytesSent"`
 	PacketsRecv uint64 `json:"packetsRecv"`
 	PacketsSent uint64 `json:"packetsSent"`
+
+	// Formatted byte counts, set by Humanize
+	BytesRecvHuman string `json:"bytesRecvHuman,omitempty"`
+	BytesSentHuman string `json:"bytesSentHuman,omitempty"`
 }
 
 // SystemInfo contains static system information.
@@ -59,66 +106,189 @@ type SystemInfo struct {
 	OSVersion    string `json:"osVersion"`
 	Kernel       string `json:"kernel"`
 	Uptime       uint64 `json:"uptime"`
+	UptimeHuman  string `json:"uptimeHuman"`
+	BootTime     uint64 `json:"bootTime"` // unix seconds
 	Architecture string `json:"architecture"`
 }
 
+// Options configures optional collectors.
+type Options struct {
+	// GPU enables NVIDIA GPU metrics via nvidia-smi when it is installed
+	GPU bool
+
+	// Disabled lists categories that GetMetrics skips
+	Disabled []string
+
+	// Mounts selects the filesystems reported in DiskMetrics.Partitions
+	Mounts MountFilter
+}
+
 // Collector gathers system metrics.
-type Collector struct{}
+type Collector struct {
+	// nvidiaSMI is the resolved nvidia-smi path, empty if GPU metrics are off
+	nvidiaSMI string
 
-// NewCollector creates a new metrics collector.
-func NewCollector() *Collector {
-	return &Collector{}
+	// disabled holds the categories GetMetrics skips
+	disabled map[string]bool
+
+	// mountFilter selects the partitions reported with disk metrics
+	mountFilter MountFilter
+
+	// prevDiskIO is the last disk I/O sample, used to compute rates
+	diskIOMu   sync.Mutex
+	prevDiskIO diskIOSample
+
+	// tcpSummary is the last TCP state count, reused for tcpSummaryTTL
+	tcpSummaryMu sync.Mutex
+	tcpSummary   *TCPSummary
+
+	// prevIface holds the last sample of each interface queried by name
+	ifaceMu   sync.Mutex
+	prevIface map[string]ifaceSample
+
+	// sysInfo caches the static parts of SystemInfo until sysInfoExpiry
+	sysInfoMu     sync.Mutex
+	sysInfo       *SystemInfo
+	sysInfoExpiry time.Time
+
+	// snapshot is the last full sample taken by CachedMetrics; snapshotRefreshMu
+	// serializes collection so concurrent callers share one
+	snapshotRefreshMu sync.Mutex
+	snapshotMu        sync.Mutex
+	snapshot          *Metrics
 }
 
-// GetMetrics gathers and returns current system metrics.
-func (c *Collector) GetMetrics() (*Metrics, error) {
-	cpuMetrics, err := c.getCPUMetrics()
-	if err != nil {
-		return nil, err
+// systemInfoTTL is how long static system information is cached.
+const systemInfoTTL = 5 * time.Minute
+
+// hostInfo and hostUptime read system information; tests replace them.
+var (
+	hostInfo   = host.Info
+	hostUptime = host.Uptime
+)
+
+// NewCollector creates a new metrics collector.
+func NewCollector(opts Options) *Collector {
+	c := &Collector{disabled: make(map[string]bool), mountFilter: opts.Mounts}
+	for _, category := range opts.Disabled {
+		c.disabled[category] = true
 	}
 
-	memMetrics, err := c.getMemoryMetrics()
-	if err != nil {
-		return nil, err
+	if opts.GPU {
+		if path, err := exec.LookPath("nvidia-smi"); err == nil {
+			slog.Info("GPU metrics enabled", "nvidiaSmi", path)
+			c.nvidiaSMI = path
+		} else {
+			slog.Debug("nvidia-smi not found, GPU metrics disabled")
+		}
 	}
 
-	diskMetrics, err := c.getDiskMetrics()
-	if err != nil {
-		return nil, err
+	return c
+}
+
+// GetMetrics gathers and returns current system metrics, skipping disabled
+// categories. Collection is best-effort: a failing category is left nil and
+// reported in Errors, and an error is returned only if every category failed.
+func (c *Collector) GetMetrics() (*Metrics, error) {
+	m := &Metrics{}
+	attempted := 0
+	var lastErr error
+
+	collect := func(category string, get func() error) {
+		if c.disabled[category] {
+			return
+		}
+		attempted++
+		if err := get(); err != nil {
+			slog.Warn("Failed to collect metrics", "category", category, "error", err)
+			if m.Errors == nil {
+				m.Errors = make(map[string]string)
+			}
+			m.Errors[category] = err.Error()
+			lastErr = err
+		}
 	}
 
-	netMetrics, err := c.getNetworkMetrics()
-	if err != nil {
-		return nil, err
+	collect(CategoryCPU, func() (err error) { m.CPU, err = c.GetCPUMetrics(); return })
+	collect(CategoryMemory, func() (err error) { m.Memory, err = c.GetMemoryMetrics(); return })
+	collect(CategoryDisk, func() (err error) { m.Disk, err = c.GetDiskMetrics(); return })
+	collect(CategoryNetwork, func() (err error) { m.Network, err = c.GetNetworkMetrics(); return })
+
+	if attempted > 0 && len(m.Errors) == attempted {
+		return nil, fmt.Errorf("all metric collectors failed: %w", lastErr)
 	}
 
-	return &Metrics{
-		CPU:       *cpuMetrics,
-		Memory:    *memMetrics,
-		Disk:      *diskMetrics,
-		Network:   *netMetrics,
-		Timestamp: time.Now().UnixMilli(),
-	}, nil
+	m.GPU = c.getGPUMetrics()
+	m.Timestamp = time.Now().UnixMilli()
+	return m, nil
 }
 
-// GetSystemInfo returns static system information.
+// Check verifies that metrics can be collected, using a cheap memory read.
+func (c *Collector) Check() error {
+	_, err := c.GetMemoryMetrics()
+	return err
+}
+
+// GetSystemInfo returns system information. Static fields are cached for
+// systemInfoTTL; uptime is recomputed on every call.
 func (c *Collector) GetSystemInfo() (*SystemInfo, error) {
-	info, err := host.Info()
-	if err != nil {
-		return nil, err
+	c.sysInfoMu.Lock()
+	defer c.sysInfoMu.Unlock()
+
+	if c.sysInfo == nil || time.Now().After(c.sysInfoExpiry) {
+		info, err := hostInfo()
+		if err != nil {
+			return nil, err
+		}
+
+		c.sysInfo = &SystemInfo{
+			Hostname:     info.Hostname,
+			OS:           info.OS,
+			OSVersion:    info.PlatformVersion,
+			Kernel:       info.KernelVersion,
+			Uptime:       info.Uptime,
+			UptimeHuman:  formatUptime(info.Uptime),
+			BootTime:     info.BootTime,
+			Architecture: info.KernelArch,
+		}
+		c.sysInfoExpiry = time.Now().Add(systemInfoTTL)
+
+		result := *c.sysInfo
+		return &result, nil
 	}
 
-	return &SystemInfo{
-		Hostname:     info.Hostname,
-		OS:           info.OS,
-		OSVersion:    info.PlatformVersion,
-		Kernel:       info.KernelVersion,
-		Uptime:       info.Uptime,
-		Architecture: info.KernelArch,
-	}, nil
+	result := *c.sysInfo
+	if uptime, err := hostUptime(); err == nil {
+		result.Uptime = uptime
+		result.UptimeHuman = formatUptime(uptime)
+	}
+	return &result, nil
+}
+
+// formatUptime renders seconds as e.g. "3d 4h 12m".
+func formatUptime(seconds uint64) string {
+	days := seconds / 86400
+	hours := (seconds % 86400) / 3600
+	minutes := (seconds % 3600) / 60
+
+	if days > 0 {
+		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+	}
+	if hours > 0 {
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
+
+// Refresh discards cached system information so the next call re-reads it.
+func (c *Collector) Refresh() {
+	c.sysInfoMu.Lock()
+	defer c.sysInfoMu.Unlock()
+	c.sysInfo = nil
 }
 
-func (c *Collector) getCPUMetrics() (*CPUMetrics, error) {
+// GetCPUMetrics returns CPU usage sampled over one second, so it blocks for that long.
+func (c *Collector) GetCPUMetrics() (*CPUMetrics, error) {
 	// Get CPU usage percentage (1 second interval)
 	percentages, err := cpu.Percent(time.Second, false)
 	if err != nil {
@@ -156,7 +326,8 @@ func (c *Collector) getCPUMetrics() (*CPUMetrics, error) {
 	}, nil
 }
 
-func (c *Collector) getMemoryMetrics() (*MemoryMetrics, error) {
+// GetMemoryMetrics returns physical memory usage.
+func (c *Collector) GetMemoryMetrics() (*MemoryMetrics, error) {
 	v, err := mem.VirtualMemory()
 	if err != nil {
 		return nil, err
@@ -170,9 +341,11 @@ func (c *Collector) getMemoryMetrics() (*MemoryMetrics, error) {
 	}, nil
 }
 
-func (c *Collector) getDiskMetrics() (*DiskMetrics, error) {
-	// Get root partition stats
-	usage, err := disk.Usage("/")
+// GetDiskMetrics returns usage for the root filesystem and each filtered
+// partition, and per-device I/O.
+func (c *Collector) GetDiskMetrics() (*DiskMetrics, error) {
+	root := rootMount()
+	usage, err := disk.Usage(root)
 	if err != nil {
 		return nil, err
 	}
@@ -182,11 +355,37 @@ func (c *Collector) getDiskMetrics() (*DiskMetrics, error) {
 		Used:         usage.Used,
 		Free:         usage.Free,
 		UsagePercent: usage.UsedPercent,
-		MountPoint:   "/",
+		MountPoint:   root,
+
+		InodesTotal:        usage.InodesTotal,
+		InodesUsed:         usage.InodesUsed,
+		InodesFree:         usage.InodesFree,
+		InodesUsagePercent: usage.InodesUsedPercent,
+
+		IO:         c.getDiskIOMetrics(),
+		Partitions: c.GetPartitions(),
 	}, nil
 }
 
-func (c *Collector) getNetworkMetrics() (*NetworkMetrics, error) {
+// rootMount returns the filesystem reported as the main disk: "/" on Unix
+// and the system drive, usually C:\, on Windows.
+func rootMount() string {
+	return rootMountFor(runtime.GOOS, os.Getenv)
+}
+
+// rootMountFor is rootMount for the OS goos, reading the environment with getenv.
+func rootMountFor(goos string, getenv func(string) string) string {
+	if goos != "windows" {
+		return "/"
+	}
+	if drive := getenv("SystemDrive"); drive != "" {
+		return drive + `\`
+	}
+	return `C:\`
+}
+
+// GetNetworkMetrics returns counters summed across all interfaces.
+func (c *Collector) GetNetworkMetrics() (*NetworkMetrics, error) {
 	counters, err := net.IOCounters(false)
 	if err != nil {
 		return nil, err
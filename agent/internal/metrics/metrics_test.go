@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/host"
+)
+
+func TestGetSystemInfoCachesStaticFields(t *testing.T) {
+	infoCalls := 0
+	uptime := uint64(3600)
+	hostInfo = func() (*host.InfoStat, error) {
+		infoCalls++
+		return &host.InfoStat{Hostname: "web-1", OS: "linux", KernelVersion: "6.1", Uptime: uptime}, nil
+	}
+	hostUptime = func() (uint64, error) { return uptime, nil }
+	t.Cleanup(func() { hostInfo, hostUptime = host.Info, host.Uptime })
+
+	c := NewCollector(Options{})
+	first, err := c.GetSystemInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Hostname != "web-1" || first.UptimeHuman != "1h 0m" {
+		t.Fatalf("got %+v", first)
+	}
+
+	// Within the TTL static fields are reused but uptime is fresh
+	uptime = 7200
+	second, err := c.GetSystemInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if infoCalls != 1 {
+		t.Errorf("host info read %d times within the TTL, want 1", infoCalls)
+	}
+	if second.Uptime != 7200 || second.UptimeHuman != "2h 0m" {
+		t.Errorf("got uptime %d (%s), want 7200 (2h 0m)", second.Uptime, second.UptimeHuman)
+	}
+	if first.Uptime != 3600 {
+		t.Errorf("earlier result changed to uptime %d", first.Uptime)
+	}
+
+	// Once the TTL has passed the static fields are read again
+	c.sysInfoExpiry = time.Now().Add(-time.Second)
+	if _, err := c.GetSystemInfo(); err != nil {
+		t.Fatal(err)
+	}
+	if infoCalls != 2 {
+		t.Errorf("host info read %d times after the TTL, want 2", infoCalls)
+	}
+}
+
+func TestRootMountFor(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(key string) string { return vars[key] }
+	}
+	for _, tc := range []struct {
+		goos string
+		env  map[string]string
+		want string
+	}{
+		{"linux", nil, "/"},
+		{"darwin", nil, "/"},
+		{"freebsd", map[string]string{"SystemDrive": "D:"}, "/"},
+		{"windows", map[string]string{"SystemDrive": "D:"}, `D:\`},
+		{"windows", nil, `C:\`},
+	} {
+		if got := rootMountFor(tc.goos, env(tc.env)); got != tc.want {
+			t.Errorf("rootMountFor(%q, %v) = %q, want %q", tc.goos, tc.env, got, tc.want)
+		}
+	}
+}
+
+func TestRootMount(t *testing.T) {
+	root := rootMount()
+	switch runtime.GOOS {
+	case "windows":
+		if len(root) != 3 || root[1:] != `:\` {
+			t.Errorf("rootMount() = %q, want a drive root such as C:\\", root)
+		}
+	default:
+		if root != "/" {
+			t.Errorf("rootMount() = %q, want /", root)
+		}
+	}
+	if _, err := disk.Usage(root); err != nil {
+		t.Errorf("disk.Usage(%q): %v", root, err)
+	}
+}
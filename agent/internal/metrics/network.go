@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+// ErrInterfaceNotFound is returned when no network interface has the requested name.
+var ErrInterfaceNotFound = errors.New("network interface not found")
+
+// InterfaceMetrics contains I/O counters and rates for a single network interface.
+type InterfaceMetrics struct {
+	Name        string `json:"name"`
+	BytesRecv   uint64 `json:"bytesRecv"`
+	BytesSent   uint64 `json:"bytesSent"`
+	PacketsRecv uint64 `json:"packetsRecv"`
+	PacketsSent uint64 `json:"packetsSent"`
+	ErrorsIn    uint64 `json:"errorsIn"`
+	ErrorsOut   uint64 `json:"errorsOut"`
+	DropsIn     uint64 `json:"dropsIn"`
+	DropsOut    uint64 `json:"dropsOut"`
+
+	// Rates are computed against the previous sample of this interface and
+	// are zero on the first one
+	RecvBytesPerSec   float64 `json:"recvBytesPerSec"`
+	SentBytesPerSec   float64 `json:"sentBytesPerSec"`
+	RecvPacketsPerSec float64 `json:"recvPacketsPerSec"`
+	SentPacketsPerSec float64 `json:"sentPacketsPerSec"`
+
+	// Formatted counts and rates, set by Humanize
+	BytesRecvHuman string `json:"bytesRecvHuman,omitempty"`
+	BytesSentHuman string `json:"bytesSentHuman,omitempty"`
+	RecvRateHuman  string `json:"recvRateHuman,omitempty"`
+	SentRateHuman  string `json:"sentRateHuman,omitempty"`
+}
+
+// ifaceSample is a previous reading of one interface used to compute rates.
+type ifaceSample struct {
+	counters net.IOCountersStat
+	at       time.Time
+}
+
+// GetInterfaceMetrics returns counters and per-second rates for the named
+// interface, or ErrInterfaceNotFound.
+func (c *Collector) GetInterfaceMetrics(name string) (*InterfaceMetrics, error) {
+	counters, err := net.IOCounters(true)
+	if err != nil {
+		return nil, err
+	}
+
+	var cur *net.IOCountersStat
+	for i := range counters {
+		if counters[i].Name == name {
+			cur = &counters[i]
+			break
+		}
+	}
+	if cur == nil {
+		return nil, ErrInterfaceNotFound
+	}
+	now := time.Now()
+
+	c.ifaceMu.Lock()
+	prev, ok := c.prevIface[name]
+	if c.prevIface == nil {
+		c.prevIface = make(map[string]ifaceSample)
+	}
+	c.prevIface[name] = ifaceSample{counters: *cur, at: now}
+	c.ifaceMu.Unlock()
+
+	m := &InterfaceMetrics{
+		Name:        cur.Name,
+		BytesRecv:   cur.BytesRecv,
+		BytesSent:   cur.BytesSent,
+		PacketsRecv: cur.PacketsRecv,
+		PacketsSent: cur.PacketsSent,
+		ErrorsIn:    cur.Errin,
+		ErrorsOut:   cur.Errout,
+		DropsIn:     cur.Dropin,
+		DropsOut:    cur.Dropout,
+	}
+	if elapsed := now.Sub(prev.at).Seconds(); ok && elapsed > 0 {
+		m.RecvBytesPerSec = rate(prev.counters.BytesRecv, cur.BytesRecv, elapsed)
+		m.SentBytesPerSec = rate(prev.counters.BytesSent, cur.BytesSent, elapsed)
+		m.RecvPacketsPerSec = rate(prev.counters.PacketsRecv, cur.PacketsRecv, elapsed)
+		m.SentPacketsPerSec = rate(prev.counters.PacketsSent, cur.PacketsSent, elapsed)
+	}
+	return m, nil
+}
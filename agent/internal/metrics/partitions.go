@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// PartitionMetrics contains usage for a single mounted filesystem.
+type PartitionMetrics struct {
+	Device       string  `json:"device"`
+	MountPoint   string  `json:"mountPoint"`
+	FSType       string  `json:"fsType"`
+	ReadOnly     bool    `json:"readOnly"`
+	Total        uint64  `json:"total"`
+	Used         uint64  `json:"used"`
+	Free         uint64  `json:"free"`
+	UsagePercent float64 `json:"usagePercent"`
+
+	// Formatted sizes, set by DiskMetrics.Humanize
+	TotalHuman string `json:"totalHuman,omitempty"`
+	UsedHuman  string `json:"usedHuman,omitempty"`
+	FreeHuman  string `json:"freeHuman,omitempty"`
+
+	// Inode usage; zero on platforms without inodes
+	InodesTotal        uint64  `json:"inodesTotal"`
+	InodesUsed         uint64  `json:"inodesUsed"`
+	InodesFree         uint64  `json:"inodesFree"`
+	InodesUsagePercent float64 `json:"inodesUsagePercent"`
+}
+
+// MountFilter selects which mounted filesystems are reported. Mount patterns
+// are globs as in filepath.Match; a pattern also matches everything mounted
+// beneath a path it matches, so "/snap" covers "/snap/core/1234".
+type MountFilter struct {
+	// Include lists mount point patterns to report; empty reports all
+	Include []string
+
+	// Exclude lists mount point patterns to skip
+	Exclude []string
+
+	// ExcludeFSTypes lists filesystem types to skip, e.g. tmpfs or squashfs
+	ExcludeFSTypes []string
+}
+
+// Allows reports whether a filesystem of type fsType mounted at mountPoint passes the filter.
+func (f MountFilter) Allows(mountPoint, fsType string) bool {
+	if slices.Contains(f.ExcludeFSTypes, fsType) {
+		return false
+	}
+	if len(f.Include) > 0 && !matchMount(f.Include, mountPoint) {
+		return false
+	}
+	return !matchMount(f.Exclude, mountPoint)
+}
+
+// matchMount reports whether mountPoint or one of its parent directories matches a pattern.
+func matchMount(patterns []string, mountPoint string) bool {
+	for _, pattern := range patterns {
+		for p := mountPoint; ; p = filepath.Dir(p) {
+			if ok, _ := filepath.Match(pattern, p); ok {
+				return true
+			}
+			if parent := filepath.Dir(p); parent == p {
+				break
+			}
+		}
+	}
+	return false
+}
+
+// GetPartitions returns usage for every mounted filesystem that passes the
+// collector's mount filter. Bind mounts of a device already reported are
+// skipped. It returns nil if partitions can't be listed.
+func (c *Collector) GetPartitions() []PartitionMetrics {
+	partitions, err := disk.Partitions(true)
+	if err != nil {
+		slog.Debug("Failed to list partitions", "error", err)
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var result []PartitionMetrics
+	for _, p := range partitions {
+		if !c.mountFilter.Allows(p.Mountpoint, p.Fstype) {
+			continue
+		}
+		// Pseudo devices such as "none" are shared by unrelated mounts
+		if strings.ContainsAny(p.Device, `/\:`) {
+			if seen[p.Device] {
+				continue
+			}
+			seen[p.Device] = true
+		}
+
+		usage, err := disk.Usage(p.Mountpoint)
+		if err != nil {
+			slog.Debug("Failed to read partition usage", "mountPoint", p.Mountpoint, "error", err)
+			continue
+		}
+		result = append(result, PartitionMetrics{
+			Device:       p.Device,
+			MountPoint:   p.Mountpoint,
+			FSType:       p.Fstype,
+			ReadOnly:     slices.Contains(p.Opts, "ro"),
+			Total:        usage.Total,
+			Used:         usage.Used,
+			Free:         usage.Free,
+			UsagePercent: usage.UsedPercent,
+
+			InodesTotal:        usage.InodesTotal,
+			InodesUsed:         usage.InodesUsed,
+			InodesFree:         usage.InodesFree,
+			InodesUsagePercent: usage.InodesUsedPercent,
+		})
+	}
+	return result
+}
@@ -0,0 +1,154 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// processSampleInterval is how long process CPU usage is measured over.
+const processSampleInterval = time.Second
+
+// ErrProcessNotFound is returned when no process matches a PID or name.
+var ErrProcessNotFound = errors.New("process not found")
+
+// ProcessInfo describes a single process. Fields the agent lacks permission
+// to read are left empty.
+type ProcessInfo struct {
+	PID           int32   `json:"pid"`
+	PPID          int32   `json:"ppid"`
+	Name          string  `json:"name"`
+	Cmdline       string  `json:"cmdline"`
+	Username      string  `json:"username,omitempty"`
+	Status        string  `json:"status"`
+	CPUPercent    float64 `json:"cpuPercent"` // may exceed 100 on multiple cores
+	MemoryRSS     uint64  `json:"memoryRss"`
+	MemoryVMS     uint64  `json:"memoryVms"`
+	MemoryPercent float32 `json:"memoryPercent"`
+	OpenFiles     int32   `json:"openFiles"`
+	Threads       int32   `json:"threads"`
+	CreateTime    int64   `json:"createTime"` // unix milliseconds
+}
+
+// ProcessGroup aggregates the processes sharing a name.
+type ProcessGroup struct {
+	Name       string        `json:"name"`
+	Count      int           `json:"count"`
+	CPUPercent float64       `json:"cpuPercent"`
+	MemoryRSS  uint64        `json:"memoryRss"`
+	MemoryVMS  uint64        `json:"memoryVms"`
+	OpenFiles  int32         `json:"openFiles"`
+	Threads    int32         `json:"threads"`
+	Processes  []ProcessInfo `json:"processes"`
+}
+
+// GetProcess returns details of the process with pid, measuring its CPU usage
+// over processSampleInterval.
+func (c *Collector) GetProcess(ctx context.Context, pid int32) (*ProcessInfo, error) {
+	p, err := process.NewProcessWithContext(ctx, pid)
+	if err != nil {
+		if errors.Is(err, process.ErrorProcessNotRunning) {
+			return nil, ErrProcessNotFound
+		}
+		return nil, err
+	}
+
+	infos, err := sampleProcesses(ctx, []*process.Process{p})
+	if err != nil {
+		return nil, err
+	}
+	if len(infos) == 0 {
+		return nil, ErrProcessNotFound
+	}
+	return &infos[0], nil
+}
+
+// GetProcessesByName returns the processes named name with their totals.
+func (c *Collector) GetProcessesByName(ctx context.Context, name string) (*ProcessGroup, error) {
+	all, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var matches []*process.Process
+	for _, p := range all {
+		if n, err := p.NameWithContext(ctx); err == nil && n == name {
+			matches = append(matches, p)
+		}
+	}
+
+	infos, err := sampleProcesses(ctx, matches)
+	if err != nil {
+		return nil, err
+	}
+	if len(infos) == 0 {
+		return nil, ErrProcessNotFound
+	}
+
+	group := &ProcessGroup{Name: name, Count: len(infos), Processes: infos}
+	for _, info := range infos {
+		group.CPUPercent += info.CPUPercent
+		group.MemoryRSS += info.MemoryRSS
+		group.MemoryVMS += info.MemoryVMS
+		group.OpenFiles += info.OpenFiles
+		group.Threads += info.Threads
+	}
+	return group, nil
+}
+
+// sampleProcesses reads procs' details, measuring CPU usage across one shared
+// interval. Processes that exit meanwhile are left out.
+func sampleProcesses(ctx context.Context, procs []*process.Process) ([]ProcessInfo, error) {
+	if len(procs) == 0 {
+		return nil, nil
+	}
+
+	before := make([]float64, len(procs))
+	for i, p := range procs {
+		if t, err := p.TimesWithContext(ctx); err == nil {
+			before[i] = t.User + t.System
+		}
+	}
+	start := time.Now()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(processSampleInterval):
+	}
+	elapsed := time.Since(start).Seconds()
+
+	infos := make([]ProcessInfo, 0, len(procs))
+	for i, p := range procs {
+		if running, err := p.IsRunningWithContext(ctx); err != nil || !running {
+			continue
+		}
+		info := processInfo(ctx, p)
+		if t, err := p.TimesWithContext(ctx); err == nil {
+			info.CPUPercent = (t.User + t.System - before[i]) / elapsed * 100
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// processInfo reads everything but CPU usage, skipping unreadable fields.
+func processInfo(ctx context.Context, p *process.Process) ProcessInfo {
+	info := ProcessInfo{PID: p.Pid}
+	info.PPID, _ = p.PpidWithContext(ctx)
+	info.Name, _ = p.NameWithContext(ctx)
+	info.Cmdline, _ = p.CmdlineWithContext(ctx)
+	info.Username, _ = p.UsernameWithContext(ctx)
+	if status, err := p.StatusWithContext(ctx); err == nil && len(status) > 0 {
+		info.Status = status[0]
+	}
+	if mem, err := p.MemoryInfoWithContext(ctx); err == nil {
+		info.MemoryRSS = mem.RSS
+		info.MemoryVMS = mem.VMS
+	}
+	info.MemoryPercent, _ = p.MemoryPercentWithContext(ctx)
+	info.OpenFiles, _ = p.NumFDsWithContext(ctx)
+	info.Threads, _ = p.NumThreadsWithContext(ctx)
+	info.CreateTime, _ = p.CreateTimeWithContext(ctx)
+	return info
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+var (
+	// ErrSignalNotAllowed is returned for signals outside the allowlist.
+	ErrSignalNotAllowed = errors.New("signal not permitted")
+
+	// ErrProtectedProcess is returned when asked to signal init or the agent.
+	ErrProtectedProcess = errors.New("refusing to signal init or the agent itself")
+)
+
+// allowedSignals are the signals SignalProcess may send, by name without the
+// SIG prefix. Platforms add more in signal_unix.go.
+var allowedSignals = map[string]syscall.Signal{
+	"TERM": syscall.SIGTERM,
+	"INT":  syscall.SIGINT,
+	"HUP":  syscall.SIGHUP,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+}
+
+// ParseSignal returns the allowed signal called name, e.g. "SIGTERM" or
+// "term", along with its canonical name.
+func ParseSignal(name string) (syscall.Signal, string, error) {
+	short := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(name)), "SIG")
+	sig, ok := allowedSignals[short]
+	if !ok {
+		return 0, "", fmt.Errorf("%w: %q", ErrSignalNotAllowed, name)
+	}
+	return sig, "SIG" + short, nil
+}
+
+// SignalProcess sends sig to pid. Permission failures are returned as
+// errors matching os.ErrPermission.
+func SignalProcess(ctx context.Context, pid int32, sig syscall.Signal) error {
+	if pid <= 1 || int(pid) == os.Getpid() {
+		return ErrProtectedProcess
+	}
+
+	p, err := process.NewProcessWithContext(ctx, pid)
+	if err != nil {
+		if errors.Is(err, process.ErrorProcessNotRunning) {
+			return ErrProcessNotFound
+		}
+		return err
+	}
+	if err := p.SendSignalWithContext(ctx, sig); err != nil {
+		if errors.Is(err, syscall.ESRCH) {
+			return ErrProcessNotFound
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package metrics
+
+import "syscall"
+
+func init() {
+	allowedSignals["USR1"] = syscall.SIGUSR1
+	allowedSignals["USR2"] = syscall.SIGUSR2
+	allowedSignals["STOP"] = syscall.SIGSTOP
+	allowedSignals["CONT"] = syscall.SIGCONT
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/host"
+)
+
+// UserSession describes a logged-in user session.
+type UserSession struct {
+	Username  string `json:"username"`
+	Terminal  string `json:"terminal"`
+	Host      string `json:"host"`
+	LoginTime string `json:"loginTime"`
+}
+
+// UsersInfo lists the current user sessions.
+type UsersInfo struct {
+	Sessions int           `json:"sessions"`
+	Users    []UserSession `json:"users"`
+}
+
+// GetUsers returns the currently logged-in user sessions. Platforms where
+// sessions can't be enumerated yield an empty list rather than an error.
+func (c *Collector) GetUsers() *UsersInfo {
+	stats, err := host.Users()
+	if err != nil {
+		slog.Debug("User enumeration unavailable", "error", err)
+		return &UsersInfo{Users: []UserSession{}}
+	}
+
+	users := make([]UserSession, 0, len(stats))
+	for _, u := range stats {
+		users = append(users, UserSession{
+			Username:  u.User,
+			Terminal:  u.Terminal,
+			Host:      u.Host,
+			LoginTime: time.Unix(int64(u.Started), 0).Format(time.RFC3339),
+		})
+	}
+
+	return &UsersInfo{Sessions: len(users), Users: users}
+}
@@ -0,0 +1,101 @@
+// Package oom collects out-of-memory kill events from Docker and the kernel log.
+package oom
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event sources.
+const (
+	SourceDocker = "docker"
+	SourceKernel = "kernel"
+)
+
+// Event is a single OOM kill.
+type Event struct {
+	Source        string `json:"source"`
+	Time          int64  `json:"time"` // unix milliseconds
+	ContainerID   string `json:"containerId,omitempty"`
+	ContainerName string `json:"containerName,omitempty"`
+	Image         string `json:"image,omitempty"`
+	PID           int    `json:"pid,omitempty"`
+	Process       string `json:"process,omitempty"`
+}
+
+// maxEvents bounds how many Docker OOM events a Recorder keeps.
+const maxEvents = 100
+
+// Recorder keeps the most recent OOM events. It is safe for concurrent use.
+type Recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// Add records e, dropping the oldest event once maxEvents is reached.
+func (r *Recorder) Add(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, e)
+	if len(r.events) > maxEvents {
+		r.events = r.events[len(r.events)-maxEvents:]
+	}
+}
+
+// Events returns a copy of the recorded events, oldest first.
+func (r *Recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event(nil), r.events...)
+}
+
+// killedPattern matches the kernel OOM killer's victim line, including the
+// cgroup variant and the "Kill process" wording of older kernels.
+var killedPattern = regexp.MustCompile(`(?i)out of memory: kill(?:ed)? process (\d+) \(([^)]*)\)`)
+
+// dmesgTimeLayout is the timestamp format of dmesg --time-format=iso.
+const dmesgTimeLayout = "2006-01-02T15:04:05,000000-07:00"
+
+// KernelEvents reads OOM kills from the kernel ring buffer via dmesg.
+// Reading it usually requires root or CAP_SYSLOG.
+func KernelEvents(ctx context.Context) ([]Event, error) {
+	out, err := exec.CommandContext(ctx, "dmesg", "--time-format=iso").Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("dmesg: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("dmesg: %w", err)
+	}
+	return ParseKernelLog(string(out)), nil
+}
+
+// ParseKernelLog extracts OOM kills from dmesg --time-format=iso output.
+func ParseKernelLog(output string) []Event {
+	var events []Event
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		match := killedPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		event := Event{Source: SourceKernel, Process: match[2]}
+		event.PID, _ = strconv.Atoi(match[1])
+		if stamp, _, ok := strings.Cut(line, " "); ok {
+			if t, err := time.Parse(dmesgTimeLayout, stamp); err == nil {
+				event.Time = t.UnixMilli()
+			}
+		}
+		events = append(events, event)
+	}
+	return events
+}
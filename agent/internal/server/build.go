@@ -0,0 +1,167 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/aniket/servertui/agent/internal/docker"
+	"github.com/gorilla/websocket"
+)
+
+// maxBuildContextSize bounds the tar archive uploaded for a single build.
+const maxBuildContextSize = 1 << 30
+
+// BuildStart is the first message on /ws/docker/build.
+type BuildStart struct {
+	Action     string   `json:"action"` // must be "build"
+	Tags       []string `json:"tags,omitempty"`
+	Dockerfile string   `json:"dockerfile,omitempty"`
+	NoCache    bool     `json:"noCache,omitempty"`
+	Pull       bool     `json:"pull,omitempty"`
+}
+
+// BuildResult is sent when a build started over /ws/docker/build finishes.
+type BuildResult struct {
+	Success  bool   `json:"success"`
+	ImageID  string `json:"imageId,omitempty"`
+	Duration int64  `json:"duration"` // milliseconds
+	Error    string `json:"error,omitempty"`
+}
+
+// handleDockerBuildWS builds an image from a context uploaded over a WebSocket.
+// The client's first message must be {"action": "build", ...} naming the tags
+// and Dockerfile. It then sends the build context as a tar archive in binary
+// messages, ending it with {"action": "endContext"}, and may send "cancel" at
+// any time. Output is sent as buildLog messages, followed by buildResult,
+// after which the connection is closed.
+func (s *Server) handleDockerBuildWS(w http.ResponseWriter, r *http.Request) {
+	dm := s.dockerManager.Load()
+	if dm == nil {
+		http.Error(w, "Docker not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		slog.Warn("WebSocket upgrade failed", "error", err)
+		return
+	}
+	defer ws.Close()
+
+	conn := s.newWSConn(ws)
+	if !s.wsConns.add(conn) {
+		return
+	}
+	defer s.wsConns.remove(conn)
+
+	var start BuildStart
+	if err := conn.ReadJSON(&start); err != nil {
+		slog.Debug("Failed to read build start message", "error", err)
+		return
+	}
+	if start.Action != "build" {
+		s.sendWSMessage(conn, "error", map[string]string{"message": "first message must be a build action"})
+		return
+	}
+
+	slog.Info("Building image", "tags", start.Tags, "dockerfile", start.Dockerfile, "client", clientSubject(r), "requestId", requestID(r))
+
+	// Canceling ctx aborts the build
+	ctx, cancel := context.WithCancel(s.shutdownCtx)
+	defer cancel()
+
+	buildContext, upload := io.Pipe()
+	go func() {
+		defer cancel()
+		if err := s.readBuildContext(conn, upload); err != nil {
+			upload.CloseWithError(err)
+			return
+		}
+		// Keep reading so a late "cancel" still aborts the build
+		for {
+			var msg ClientMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			if msg.Action == "cancel" {
+				return
+			}
+		}
+	}()
+
+	out := make(chan string, logBufferSize)
+	done := make(chan BuildResult, 1)
+	go func() {
+		started := time.Now()
+		opts := docker.BuildOptions{Tags: start.Tags, Dockerfile: start.Dockerfile, NoCache: start.NoCache, Pull: start.Pull}
+		imageID, err := dm.BuildImage(ctx, buildContext, opts, out)
+		// Unblock the upload if the daemon stopped reading the context
+		buildContext.CloseWithError(errors.New("build finished"))
+		result := BuildResult{Success: err == nil, ImageID: imageID, Duration: time.Since(started).Milliseconds()}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		done <- result
+	}()
+
+	for line := range out {
+		if err := s.sendWSMessage(conn, "buildLog", map[string]string{"line": line}); err != nil {
+			slog.Debug("Failed to send build output", "error", err)
+			cancel()
+		}
+	}
+
+	result := <-done
+	if result.Success {
+		slog.Info("Image build finished", "imageId", result.ImageID, "durationMs", result.Duration, "requestId", requestID(r))
+	} else {
+		slog.Warn("Image build failed", "error", result.Error, "durationMs", result.Duration, "requestId", requestID(r))
+	}
+	s.sendWSMessage(conn, "buildResult", result)
+}
+
+// readBuildContext copies binary messages from conn to upload until the
+// client sends endContext, then closes upload. It fails if the client cancels,
+// disconnects or sends more than maxBuildContextSize bytes.
+func (s *Server) readBuildContext(conn *wsConn, upload *io.PipeWriter) error {
+	var size int64
+	for {
+		msgType, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		if msgType == websocket.BinaryMessage {
+			size += int64(len(data))
+			if size > maxBuildContextSize {
+				err := fmt.Errorf("build context exceeds %d bytes", maxBuildContextSize)
+				s.sendWSMessage(conn, "error", map[string]string{"message": err.Error()})
+				return err
+			}
+			if _, err := upload.Write(data); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var msg ClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			s.sendWSMessage(conn, "error", map[string]string{"message": "Invalid message format"})
+			continue
+		}
+		switch msg.Action {
+		case "endContext":
+			return upload.Close()
+		case "cancel":
+			return errors.New("build canceled")
+		default:
+			s.sendWSMessage(conn, "error", map[string]string{"message": "unknown action: " + msg.Action})
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// toJSONMap converts v to its generic JSON object form.
+func toJSONMap(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	err = json.Unmarshal(data, &m)
+	return m, err
+}
+
+// jsonDiff returns the fields of cur whose values differ from prev, with
+// fields missing from cur set to null. Nested objects are compared field by
+// field; arrays are compared whole.
+func jsonDiff(prev, cur map[string]any) map[string]any {
+	diff := make(map[string]any)
+	for key, value := range cur {
+		old, ok := prev[key]
+		if !ok {
+			diff[key] = value
+			continue
+		}
+
+		oldObj, oldIsObj := old.(map[string]any)
+		curObj, curIsObj := value.(map[string]any)
+		if oldIsObj && curIsObj {
+			if nested := jsonDiff(oldObj, curObj); len(nested) > 0 {
+				diff[key] = nested
+			}
+			continue
+		}
+
+		if !reflect.DeepEqual(old, value) {
+			diff[key] = value
+		}
+	}
+	for key := range prev {
+		if _, ok := cur[key]; !ok {
+			diff[key] = nil
+		}
+	}
+	return diff
+}
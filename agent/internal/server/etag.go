@@ -0,0 +1,77 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// writeJSONWithETag writes data like writeJSON with a 200 status, tagged with
+// an ETag derived from its encoding. If the request's If-None-Match already
+// names that ETag, 304 Not Modified is sent without a body instead.
+func writeJSONWithETag(w http.ResponseWriter, r *http.Request, data interface{}) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeTaggedJSON(w, r, body, etagFor(body))
+}
+
+// writeJSONWithETagOf is writeJSONWithETag with the ETag derived from key
+// instead of data, for resources with fields that change on every request,
+// like an uptime, that shouldn't defeat revalidation on their own.
+func writeJSONWithETagOf(w http.ResponseWriter, r *http.Request, data, key interface{}) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	keyJSON, err := json.Marshal(key)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeTaggedJSON(w, r, body, etagFor(keyJSON))
+}
+
+// etagFor returns the ETag for an encoded resource. It is weak because
+// gzipMiddleware may send the same resource with different bytes.
+func etagFor(b []byte) string {
+	sum := sha256.Sum256(b)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// writeTaggedJSON writes an encoded JSON body with etag, or 304 Not Modified
+// if the request's If-None-Match already names it.
+func writeTaggedJSON(w http.ResponseWriter, r *http.Request, body []byte, etag string) {
+	w.Header().Set("ETag", etag)
+	// Revalidate on every use rather than relying on heuristic freshness
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(append(body, '\n'))
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag,
+// using the weak comparison RFC 9110 specifies for If-None-Match.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
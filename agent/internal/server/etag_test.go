@@ -0,0 +1,121 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteJSONWithETag(t *testing.T) {
+	resource := map[string]string{"hostname": "web-1"}
+	srv := httptest.NewServer(gzipMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSONWithETag(w, r, resource)
+	})))
+	defer srv.Close()
+
+	get := func(ifNoneMatch string) (*http.Response, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, string(body)
+	}
+
+	first, body := get("")
+	etag := first.Header.Get("ETag")
+	if first.StatusCode != http.StatusOK || etag == "" {
+		t.Fatalf("got %d with ETag %q, want 200 with an ETag", first.StatusCode, etag)
+	}
+	// The same tag covers identity and gzip encodings, so it must be weak
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Errorf("ETag %q isn't weak", etag)
+	}
+	if body != "{\"hostname\":\"web-1\"}\n" {
+		t.Errorf("body = %q", body)
+	}
+
+	// Unchanged resource: 304 with the same ETag and no body
+	resp, body := get(etag)
+	if resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("status = %d, want 304", resp.StatusCode)
+	}
+	if body != "" {
+		t.Errorf("304 has body %q", body)
+	}
+	if got := resp.Header.Get("ETag"); got != etag {
+		t.Errorf("304 ETag = %q, want %q", got, etag)
+	}
+
+	// Changed resource: the old ETag no longer matches
+	resource["hostname"] = "web-2"
+	resp, body = get(etag)
+	if resp.StatusCode != http.StatusOK || body != "{\"hostname\":\"web-2\"}\n" {
+		t.Errorf("got %d %q, want 200 with the new body", resp.StatusCode, body)
+	}
+	if resp.Header.Get("ETag") == etag {
+		t.Error("ETag didn't change with the resource")
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	const etag = `W/"abc"`
+	for _, tc := range []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{`"abc"`, true},
+		{`W/"abc"`, true},
+		{`"xyz", "abc"`, true},
+		{`"xyz"`, false},
+		{`abc`, false},
+		{`*`, true},
+	} {
+		if got := etagMatches(tc.header, etag); got != tc.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tc.header, got, tc.want)
+		}
+	}
+}
+
+func TestSystemInfoETag(t *testing.T) {
+	_, srv := newTestServer(t, nil)
+
+	get := func(ifNoneMatch string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/system", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	first := get("")
+	etag := first.Header.Get("ETag")
+	if first.StatusCode != http.StatusOK || etag == "" {
+		t.Fatalf("got %d with ETag %q, want 200 with an ETag", first.StatusCode, etag)
+	}
+
+	// The uptime has advanced, but the static fields haven't changed
+	time.Sleep(1100 * time.Millisecond)
+	if resp := get(etag); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("status = %d, want 304", resp.StatusCode)
+	}
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/aniket/servertui/agent/internal/updates"
+)
+
+// ExecExit is sent when a command started over /ws/exec finishes.
+type ExecExit struct {
+	ExitCode int    `json:"exitCode"`
+	Duration int64  `json:"duration"` // milliseconds
+	TimedOut bool   `json:"timedOut,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// handleExecWS runs a command and streams its output over a WebSocket.
+// The client's first message must be {"action": "start", "command": ...};
+// the command is checked against the exec policy like /api/exec. Afterwards
+// the client may send "stdin" (with data), "closeStdin" and "kill". Output is
+// sent as execOutput messages, followed by execExit, after which the
+// connection is closed.
+func (s *Server) handleExecWS(w http.ResponseWriter, r *http.Request) {
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		slog.Warn("WebSocket upgrade failed", "error", err)
+		return
+	}
+	defer ws.Close()
+
+	conn := s.newWSConn(ws)
+	if !s.wsConns.add(conn) {
+		return
+	}
+	defer s.wsConns.remove(conn)
+
+	var start ClientMessage
+	if err := conn.ReadJSON(&start); err != nil {
+		slog.Debug("Failed to read exec start message", "error", err)
+		return
+	}
+	if start.Action != "start" || start.Command == "" {
+		s.sendWSMessage(conn, "error", map[string]string{"message": "first message must be a start action with a command"})
+		return
+	}
+	if err := s.execPolicy.Load().Check(start.Command); err != nil {
+		slog.Warn("Rejected exec request", "command", start.Command, "client", clientSubject(r), "requestId", requestID(r), "error", err)
+		s.sendWSMessage(conn, "error", map[string]string{"message": err.Error()})
+		return
+	}
+
+	opts := updates.ExecOptions{Dir: start.Dir, Env: start.Env}
+	if err := s.checkExecOptions(opts); err != nil {
+		slog.Warn("Rejected exec request", "command", start.Command, "dir", start.Dir, "client", clientSubject(r), "requestId", requestID(r), "error", err)
+		s.sendWSMessage(conn, "error", map[string]string{"message": err.Error()})
+		return
+	}
+
+	slog.Info("Executing streamed command", "command", start.Command, "dir", start.Dir, "client", clientSubject(r), "requestId", requestID(r))
+
+	// Canceling ctx kills the command
+	ctx, cancel := context.WithCancel(s.shutdownCtx)
+	defer cancel()
+
+	stdin := make(chan []byte)
+	go func() {
+		defer cancel()
+		stdinOpen := true
+		for {
+			var msg ClientMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			switch msg.Action {
+			case "stdin":
+				if !stdinOpen {
+					continue
+				}
+				select {
+				case stdin <- []byte(msg.Data):
+				case <-ctx.Done():
+					return
+				}
+			case "closeStdin":
+				if stdinOpen {
+					close(stdin)
+					stdinOpen = false
+				}
+			case "kill":
+				return
+			default:
+				s.sendWSMessage(conn, "error", map[string]string{"message": "unknown action: " + msg.Action})
+			}
+		}
+	}()
+
+	out := make(chan updates.OutputLine, logBufferSize)
+	done := make(chan ExecExit, 1)
+	go func() {
+		result, err := s.updatesManager.StreamCommand(ctx, start.Command, opts, stdin, out)
+		var exit ExecExit
+		if result != nil {
+			exit = ExecExit{ExitCode: result.ExitCode, Duration: result.Duration, TimedOut: result.TimedOut, Error: result.Stderr}
+		}
+		if err != nil {
+			exit.Error = err.Error()
+		}
+		done <- exit
+	}()
+
+	for line := range out {
+		if err := s.sendWSMessage(conn, "execOutput", line); err != nil {
+			slog.Debug("Failed to send exec output", "error", err)
+			cancel()
+		}
+	}
+
+	exit := <-done
+	slog.Info("Streamed command finished", "exitCode", exit.ExitCode, "requestId", requestID(r))
+	s.sendWSMessage(conn, "execExit", exit)
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+// gzipMinSize is the response size below which compression isn't worth it.
+const gzipMinSize = 1024
+
+// gzipMiddleware compresses responses of at least gzipMinSize bytes for
+// clients that accept gzip. WebSocket upgrades are passed through untouched.
+func gzipMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") ||
+			strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		gw := &gzipResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(gw, r)
+		// Not deferred: if the handler panics, nothing buffered is sent and
+		// recoverMiddleware can still write its 500
+		gw.close()
+	})
+}
+
+// gzipResponseWriter buffers the start of a response until it knows whether
+// the body is large enough to compress, then either streams through gzip or
+// writes the buffered bytes as-is.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	buf        []byte
+	gz         *gzip.Writer
+	plain      bool // committed to an uncompressed response
+}
+
+func (g *gzipResponseWriter) WriteHeader(code int) {
+	g.statusCode = code
+}
+
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	switch {
+	case g.gz != nil:
+		return g.gz.Write(p)
+	case g.plain:
+		return g.ResponseWriter.Write(p)
+	}
+
+	g.buf = append(g.buf, p...)
+	if len(g.buf) >= gzipMinSize {
+		if err := g.start(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// start begins the response, compressing it unless the handler already set an encoding.
+func (g *gzipResponseWriter) start() error {
+	h := g.Header()
+	if h.Get("Content-Type") == "" {
+		h.Set("Content-Type", http.DetectContentType(g.buf))
+	}
+
+	buf := g.buf
+	g.buf = nil
+	if h.Get("Content-Encoding") != "" {
+		return g.startPlain(buf)
+	}
+
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+	g.ResponseWriter.WriteHeader(g.statusCode)
+	g.gz = gzip.NewWriter(g.ResponseWriter)
+	_, err := g.gz.Write(buf)
+	return err
+}
+
+// startPlain begins an uncompressed response with buf as its first bytes.
+func (g *gzipResponseWriter) startPlain(buf []byte) error {
+	g.plain = true
+	g.ResponseWriter.WriteHeader(g.statusCode)
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := g.ResponseWriter.Write(buf)
+	return err
+}
+
+// Flush sends buffered data to the client. A flush before the size threshold
+// is reached commits the response to being uncompressed.
+func (g *gzipResponseWriter) Flush() {
+	switch {
+	case g.gz != nil:
+		g.gz.Flush()
+	case !g.plain:
+		buf := g.buf
+		g.buf = nil
+		g.startPlain(buf)
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// close finishes the response once the handler returns.
+func (g *gzipResponseWriter) close() {
+	switch {
+	case g.gz != nil:
+		g.gz.Close()
+	case !g.plain:
+		g.startPlain(g.buf)
+	}
+}
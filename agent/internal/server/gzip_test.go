@@ -0,0 +1,127 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// serveGzip runs a request that accepts gzip through gzipMiddleware.
+func serveGzip(h http.HandlerFunc) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	rec := httptest.NewRecorder()
+	gzipMiddleware(h).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGzipMiddleware(t *testing.T) {
+	small := strings.Repeat("a", gzipMinSize-1)
+	large := strings.Repeat("a", gzipMinSize*4)
+
+	t.Run("below threshold stays plain", func(t *testing.T) {
+		rec := serveGzip(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, small)
+		})
+		if enc := rec.Header().Get("Content-Encoding"); enc != "" {
+			t.Errorf("Content-Encoding = %q, want none", enc)
+		}
+		if rec.Code != http.StatusCreated || rec.Body.String() != small {
+			t.Errorf("got %d with %d bytes, want 201 with the body unchanged", rec.Code, rec.Body.Len())
+		}
+	})
+
+	t.Run("above threshold is compressed", func(t *testing.T) {
+		rec := serveGzip(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Length", "4096")
+			// Written in pieces so the threshold is crossed mid-response
+			for i := 0; i < 4; i++ {
+				io.WriteString(w, large[:gzipMinSize])
+			}
+		})
+		if enc := rec.Header().Get("Content-Encoding"); enc != "gzip" {
+			t.Fatalf("Content-Encoding = %q, want gzip", enc)
+		}
+		if cl := rec.Header().Get("Content-Length"); cl != "" {
+			t.Errorf("Content-Length %q kept for a compressed body", cl)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		if vary := rec.Header().Get("Vary"); vary != "Accept-Encoding" {
+			t.Errorf("Vary = %q, want Accept-Encoding", vary)
+		}
+		zr, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != large {
+			t.Errorf("decompressed %d bytes, want %d", len(body), len(large))
+		}
+	})
+
+	t.Run("existing encoding passes through", func(t *testing.T) {
+		rec := serveGzip(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Encoding", "br")
+			io.WriteString(w, large)
+		})
+		if enc := rec.Header().Get("Content-Encoding"); enc != "br" {
+			t.Errorf("Content-Encoding = %q, want br", enc)
+		}
+		if rec.Body.String() != large {
+			t.Error("body was modified")
+		}
+	})
+
+	t.Run("client without gzip", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/test", nil)
+		rec := httptest.NewRecorder()
+		gzipMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, large)
+		})).ServeHTTP(rec, req)
+		if enc := rec.Header().Get("Content-Encoding"); enc != "" {
+			t.Errorf("Content-Encoding = %q, want none", enc)
+		}
+	})
+}
+
+func TestGzipMiddlewareBypassesWebSocket(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(gzipMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		ws.WriteMessage(websocket.TextMessage, []byte(strings.Repeat("a", gzipMinSize*2)))
+	})))
+	defer srv.Close()
+
+	header := http.Header{"Accept-Encoding": {"gzip"}}
+	ws, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), header)
+	if err != nil {
+		t.Fatalf("upgrade through gzip middleware failed: %v", err)
+	}
+	defer ws.Close()
+	if enc := resp.Header.Get("Content-Encoding"); enc != "" {
+		t.Errorf("upgrade response has Content-Encoding %q", enc)
+	}
+	_, msg, err := ws.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msg) != gzipMinSize*2 {
+		t.Errorf("read %d bytes, want %d", len(msg), gzipMinSize*2)
+	}
+}
@@ -1,12 +1,34 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/aniket/servertui/agent/internal/alerts"
+	"github.com/aniket/servertui/agent/internal/diagnostics"
 	"github.com/aniket/servertui/agent/internal/docker"
+	"github.com/aniket/servertui/agent/internal/execpolicy"
+	"github.com/aniket/servertui/agent/internal/host"
+	"github.com/aniket/servertui/agent/internal/metrics"
+	"github.com/aniket/servertui/agent/internal/oom"
+	"github.com/aniket/servertui/agent/internal/services"
 	"github.com/aniket/servertui/agent/internal/updates"
+	"github.com/aniket/servertui/agent/internal/version"
 	"github.com/gorilla/mux"
 )
 
@@ -15,12 +37,49 @@ type HealthResponse struct {
 	Status string `json:"status"`
 }
 
+// ReadinessResponse represents the readiness check response.
+type ReadinessResponse struct {
+	Status     string            `json:"status"`
+	Components map[string]string `json:"components"`
+}
+
+// ContainerPage is a page of containers with the total count before paging.
+type ContainerPage struct {
+	Containers []docker.Container `json:"containers"`
+	Total      int                `json:"total"`
+	Limit      int                `json:"limit"`
+	Offset     int                `json:"offset"`
+}
+
+// ImagePage is a page of images with the total count before paging.
+type ImagePage struct {
+	Images []docker.Image `json:"images"`
+	Total  int            `json:"total"`
+	Limit  int            `json:"limit"`
+	Offset int            `json:"offset"`
+}
+
 // ExecRequest represents a command execution request.
 type ExecRequest struct {
 	Command string `json:"command"`
+	// Dir is the working directory, which must be permitted by --exec-dirs
+	Dir string `json:"dir,omitempty"`
+	// Env adds to or overrides the agent's environment
+	Env map[string]string `json:"env,omitempty"`
+}
+
+// SignalRequest asks for a signal to be sent to a process.
+type SignalRequest struct {
+	Signal string `json:"signal"`
 }
 
-// ApplyUpdateRequest represents an update request.
+// SignalResponse reports a signal that was sent.
+type SignalResponse struct {
+	PID    int32  `json:"pid"`
+	Signal string `json:"signal"`
+}
+
+// ApplyUpdateRequest represents an update, install or remove request.
 type ApplyUpdateRequest struct {
 	Package string `json:"package"`
 }
@@ -42,158 +101,1567 @@ func writeError(w http.ResponseWriter, status int, message string) {
 	writeJSON(w, status, ErrorResponse{Error: message})
 }
 
+// decodeJSON decodes the request body into v. On failure it writes 413 for
+// bodies over the size limit or 400 otherwise, and returns false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == nil {
+		return true
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit))
+		return false
+	}
+	writeError(w, http.StatusBadRequest, "invalid request body")
+	return false
+}
+
+// writeCommandResult writes the result of a command execution.
+// Timed-out commands are reported as 504 along with their partial output.
+func writeCommandResult(w http.ResponseWriter, result *updates.CommandResult, err error) {
+	if errors.Is(err, updates.ErrCommandTimeout) {
+		slog.Error("Command timed out", "error", err)
+		writeJSON(w, http.StatusGatewayTimeout, result)
+		return
+	}
+	if errors.Is(err, updates.ErrPackageHeld) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if errors.Is(err, updates.ErrInvalidPackageName) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
 // handleHealth handles the health check endpoint.
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	log.Println("[HANDLER] Health check requested")
+	slog.Debug("Health check requested")
 	writeJSON(w, http.StatusOK, HealthResponse{Status: "ok"})
 }
 
+// handleAgentVersion returns the agent's version and build information.
+func (s *Server) handleAgentVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, version.Get())
+}
+
+// AgentRuntime reports the health of the agent process itself.
+type AgentRuntime struct {
+	Uptime     int64            `json:"uptime"` // seconds since the agent started
+	Goroutines int              `json:"goroutines"`
+	GoMaxProcs int              `json:"goMaxProcs"`
+	WebSockets int              `json:"webSockets"` // active WebSocket connections
+	Memory     AgentMemoryStats `json:"memory"`
+	GC         AgentGCStats     `json:"gc"`
+}
+
+// AgentMemoryStats are the agent's memory statistics in bytes.
+type AgentMemoryStats struct {
+	Alloc       uint64 `json:"alloc"`
+	TotalAlloc  uint64 `json:"totalAlloc"`
+	Sys         uint64 `json:"sys"`
+	HeapInuse   uint64 `json:"heapInuse"`
+	HeapObjects uint64 `json:"heapObjects"`
+	StackInuse  uint64 `json:"stackInuse"`
+}
+
+// AgentGCStats summarizes the agent's garbage collections.
+type AgentGCStats struct {
+	NumGC        uint32  `json:"numGc"`
+	LastGC       int64   `json:"lastGc,omitempty"` // unix milliseconds
+	PauseTotalMs float64 `json:"pauseTotalMs"`
+	LastPauseMs  float64 `json:"lastPauseMs"`
+	CPUFraction  float64 `json:"cpuFraction"`
+	NextGC       uint64  `json:"nextGc"` // heap size target in bytes
+}
+
+// AgentConfigResponse is the configuration the agent is running with.
+type AgentConfigResponse struct {
+	Instance   string        `json:"instance"`
+	ConfigFile string        `json:"configFile,omitempty"`
+	Distro     string        `json:"distro"`
+	Features   AgentFeatures `json:"features"`
+
+	// Settings maps each flag name to its effective value, with secrets redacted
+	Settings map[string]string `json:"settings"`
+
+	// PendingRestart lists settings changed by a reload that need a restart to apply
+	PendingRestart []string `json:"pendingRestart,omitempty"`
+}
+
+// AgentFeatures summarizes which optional features are on.
+type AgentFeatures struct {
+	Exec           bool `json:"exec"`
+	HostControl    bool `json:"hostControl"`
+	Docker         bool `json:"docker"` // the Docker daemon is currently reachable
+	GPU            bool `json:"gpu"`
+	History        bool `json:"history"`
+	Alerts         bool `json:"alerts"`
+	UpdatesRefresh bool `json:"updatesRefresh"`
+	Pprof          bool `json:"pprof"`
+	Prometheus     bool `json:"prometheus"`
+	TLS            bool `json:"tls"`
+	ClientAuth     bool `json:"clientAuth"`
+}
+
+// handleAgentConfig returns the effective configuration after merging
+// defaults, the config file, flags and reloads.
+func (s *Server) handleAgentConfig(w http.ResponseWriter, r *http.Request) {
+	effective := s.effective.Load()
+	cfg := effective.cfg
+	writeJSON(w, http.StatusOK, AgentConfigResponse{
+		Instance:   s.instance,
+		ConfigFile: cfg.ConfigPath,
+		Distro:     string(s.updatesManager.GetDistro()),
+		Features: AgentFeatures{
+			Exec:           !cfg.ExecDisabled,
+			HostControl:    cfg.HostControl,
+			Docker:         s.dockerManager.Load() != nil,
+			GPU:            cfg.GPUMetrics,
+			History:        s.history != nil,
+			Alerts:         len(cfg.AlertRules) > 0,
+			UpdatesRefresh: cfg.UpdatesRefreshInterval > 0,
+			Pprof:          cfg.EnablePprof,
+			Prometheus:     cfg.Prometheus,
+			TLS:            s.tlsConfig != nil,
+			ClientAuth:     cfg.ClientCAPath != "",
+		},
+		Settings:       cfg.Settings(),
+		PendingRestart: effective.pendingRestart,
+	})
+}
+
+// handleAgentRuntime returns the agent's goroutine count, memory and GC
+// statistics, and active WebSocket connections.
+func (s *Server) handleAgentRuntime(w http.ResponseWriter, r *http.Request) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	resp := AgentRuntime{
+		Uptime:     int64(time.Since(s.startTime).Seconds()),
+		Goroutines: runtime.NumGoroutine(),
+		GoMaxProcs: runtime.GOMAXPROCS(0),
+		WebSockets: s.wsConns.count(),
+		Memory: AgentMemoryStats{
+			Alloc:       ms.Alloc,
+			TotalAlloc:  ms.TotalAlloc,
+			Sys:         ms.Sys,
+			HeapInuse:   ms.HeapInuse,
+			HeapObjects: ms.HeapObjects,
+			StackInuse:  ms.StackInuse,
+		},
+		GC: AgentGCStats{
+			NumGC:        ms.NumGC,
+			PauseTotalMs: float64(ms.PauseTotalNs) / 1e6,
+			CPUFraction:  ms.GCCPUFraction,
+			NextGC:       ms.NextGC,
+		},
+	}
+	if ms.NumGC > 0 {
+		resp.GC.LastGC = int64(ms.LastGC / 1e6)
+		resp.GC.LastPauseMs = float64(ms.PauseNs[(ms.NumGC+255)%256]) / 1e6
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleReady handles the readiness check endpoint.
+// Unlike /health it probes each subsystem and returns 503 if any is broken.
+// Docker is only probed when it was available at startup.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	resp := ReadinessResponse{Status: "ok", Components: map[string]string{}}
+
+	if err := s.metricsCollector.Check(); err != nil {
+		resp.Status = "unavailable"
+		resp.Components["metrics"] = err.Error()
+	} else {
+		resp.Components["metrics"] = "ok"
+	}
+
+	dm := s.dockerManager.Load()
+	if dm == nil {
+		resp.Components["docker"] = "disabled"
+	} else {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+		if err := dm.Ping(ctx); err != nil {
+			resp.Status = "unavailable"
+			resp.Components["docker"] = err.Error()
+		} else {
+			resp.Components["docker"] = "ok"
+		}
+	}
+
+	if resp.Status != "ok" {
+		slog.Warn("Readiness check failed", "components", resp.Components)
+		writeJSON(w, http.StatusServiceUnavailable, resp)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
 // handleSystemInfo handles the system info endpoint.
+// ?refresh=true bypasses the cached static fields. Responses carry an ETag
+// of the static fields only, so a revalidated response keeps the uptime it
+// was fetched with; clients can derive the current uptime from bootTime.
 func (s *Server) handleSystemInfo(w http.ResponseWriter, r *http.Request) {
-	log.Println("[HANDLER] System info requested")
+	slog.Debug("System info requested")
+	if r.URL.Query().Get("refresh") == "true" {
+		s.metricsCollector.Refresh()
+	}
 	info, err := s.metricsCollector.GetSystemInfo()
 	if err != nil {
-		log.Printf("[ERROR] Failed to get system info: %v", err)
+		slog.Error("Failed to get system info", "error", err)
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	log.Printf("[HANDLER] System info: hostname=%s, os=%s", info.Hostname, info.OS)
-	writeJSON(w, http.StatusOK, info)
+	slog.Debug("System info collected", "hostname", info.Hostname, "os", info.OS)
+	static := *info
+	static.Uptime, static.UptimeHuman = 0, ""
+	writeJSONWithETagOf(w, r, info, static)
+}
+
+// handleUsers handles the logged-in users endpoint.
+func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request) {
+	slog.Debug("Logged-in users requested")
+	writeJSON(w, http.StatusOK, s.metricsCollector.GetUsers())
+}
+
+// handleConnections lists open sockets. ?state=LISTEN keeps only sockets in
+// that state.
+func (s *Server) handleConnections(w http.ResponseWriter, r *http.Request) {
+	state := strings.ToUpper(r.URL.Query().Get("state"))
+	if state != "" && !slices.Contains(metrics.ConnectionStates, state) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid state %q: must be one of %s", state, strings.Join(metrics.ConnectionStates, ", ")))
+		return
+	}
+
+	conns, err := s.metricsCollector.GetConnections(r.Context(), state)
+	if err != nil {
+		slog.Error("Failed to list connections", "error", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, conns)
+}
+
+// handleTCPSummary returns TCP socket counts by state.
+func (s *Server) handleTCPSummary(w http.ResponseWriter, r *http.Request) {
+	summary, err := s.metricsCollector.GetTCPSummary(r.Context())
+	if err != nil {
+		slog.Error("Failed to summarize TCP connections", "error", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// ConnectivityResponse is the response for the connectivity self-check.
+type ConnectivityResponse struct {
+	OK     bool                 `json:"ok"` // every check succeeded
+	Checks []diagnostics.Result `json:"checks"`
+}
+
+// handleConnectivity runs the configured DNS, TCP and HTTP checks.
+func (s *Server) handleConnectivity(w http.ResponseWriter, r *http.Request) {
+	resp := ConnectivityResponse{OK: true, Checks: s.connectivity.Run(r.Context())}
+	for _, res := range resp.Checks {
+		if !res.Success {
+			resp.OK = false
+			slog.Debug("Connectivity check failed", "kind", res.Kind, "target", res.Target, "error", res.Error)
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// DiskProbeResponse is the response for the disk health probe.
+type DiskProbeResponse struct {
+	OK     bool                     `json:"ok"` // every probe succeeded
+	Probes []diagnostics.DiskResult `json:"probes"`
+}
+
+// handleDiskProbe writes, syncs and reads back a small file on each probed
+// directory, catching filesystems that went read-only or stopped responding.
+func (s *Server) handleDiskProbe(w http.ResponseWriter, r *http.Request) {
+	var targets []diagnostics.DiskTarget
+	if paths := s.config.DiskProbePathsList(); len(paths) > 0 {
+		for _, path := range paths {
+			targets = append(targets, diagnostics.DiskTarget{Path: path})
+		}
+	} else {
+		for _, p := range s.metricsCollector.GetPartitions() {
+			targets = append(targets, diagnostics.DiskTarget{Path: p.MountPoint, ReadOnly: p.ReadOnly})
+		}
+	}
+
+	resp := DiskProbeResponse{OK: true, Probes: diagnostics.ProbeDisks(r.Context(), targets, s.config.DiskProbeSize, s.config.DiagTimeout)}
+	for _, res := range resp.Probes {
+		if !res.Success {
+			resp.OK = false
+			slog.Warn("Disk probe failed", "path", res.Path, "readOnly", res.ReadOnly, "error", res.Error)
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
-// handleMetrics handles the metrics endpoint.
+// handleMetrics handles the metrics endpoint. Bursts of requests within a
+// metrics interval share one sample; X-Sampled-At says when it was taken.
 func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
-	log.Println("[HANDLER] Metrics requested")
-	m, err := s.metricsCollector.GetMetrics()
+	slog.Debug("Metrics requested")
+	m, err := s.cachedMetrics()
 	if err != nil {
-		log.Printf("[ERROR] Failed to get metrics: %v", err)
+		slog.Error("Failed to get metrics", "error", err)
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	log.Printf("[HANDLER] Metrics collected: CPU=%.2f%%, Memory=%.2f%%", m.CPU.UsagePercent, m.Memory.UsagePercent)
+	slog.Debug("Metrics collected", "timestamp", m.Timestamp)
+	w.Header().Set("X-Sampled-At", time.UnixMilli(m.Timestamp).UTC().Format(time.RFC3339Nano))
+	if humanRequested(r) {
+		m.Humanize()
+	}
 	writeJSON(w, http.StatusOK, m)
 }
 
-// handleDocker handles the Docker status endpoint.
-func (s *Server) handleDocker(w http.ResponseWriter, r *http.Request) {
-	log.Println("[HANDLER] Docker status requested")
-	if s.dockerManager == nil {
-		log.Println("[HANDLER] Docker not available, returning empty status")
-		writeJSON(w, http.StatusOK, docker.Status{
-			Installed:  false,
-			Containers: []docker.Container{},
-			Images:     []docker.Image{},
-		})
+// cachedMetrics returns a metrics snapshot no older than 90% of the metrics
+// interval, so a client polling at the streaming cadence still sees a new
+// sample on every request.
+func (s *Server) cachedMetrics() (*metrics.Metrics, error) {
+	m, err := s.metricsCollector.CachedMetrics(time.Duration(s.metricsInterval.Load()) * 9 / 10)
+	if err != nil {
+		return nil, err
+	}
+	m.Instance = s.instance
+	return m, nil
+}
+
+// humanRequested reports whether ?human=true asks for formatted sizes and
+// rounded percentages alongside the raw values.
+func humanRequested(r *http.Request) bool {
+	return r.URL.Query().Get("human") == "true"
+}
+
+// MetricsHistoryResponse is the response for the metrics history endpoint.
+type MetricsHistoryResponse struct {
+	Interval   int64                   `json:"interval"`   // sampling interval in milliseconds
+	Resolution int64                   `json:"resolution"` // bucket width in milliseconds, 0 if raw
+	Samples    []metrics.HistorySample `json:"samples"`
+}
+
+// handleMetricsHistory returns sampled metrics history.
+// since is RFC3339, unix seconds, or a duration ago (default: all retained);
+// resolution is a duration that samples are averaged over.
+func (s *Server) handleMetricsHistory(w http.ResponseWriter, r *http.Request) {
+	if s.history == nil {
+		writeError(w, http.StatusServiceUnavailable, "Metrics history disabled")
+		return
+	}
+
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := parseSince(v, time.Now())
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		since = t
+	}
+
+	var resolution time.Duration
+	if v := r.URL.Query().Get("resolution"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			writeError(w, http.StatusBadRequest, "invalid resolution")
+			return
+		}
+		resolution = d
+	}
+
+	writeJSON(w, http.StatusOK, MetricsHistoryResponse{
+		Interval:   s.config.HistoryInterval.Milliseconds(),
+		Resolution: resolution.Milliseconds(),
+		Samples:    s.history.Since(since, resolution),
+	})
+}
+
+// parseSince parses an RFC3339 timestamp, unix seconds, or a duration before now.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid since %q: expected RFC3339 timestamp, unix seconds or duration like 10m", value)
+}
+
+// handleMetric returns a handler serving a single metrics category from get.
+func handleMetric[T any](name string, get func() (*T, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		slog.Debug("Metric requested", "category", name)
+		m, err := get()
+		if err != nil {
+			slog.Error("Failed to get metric", "category", name, "error", err)
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if h, ok := any(m).(interface{ Humanize() }); ok && humanRequested(r) {
+			h.Humanize()
+		}
+		writeJSON(w, http.StatusOK, m)
+	}
+}
+
+// handleNetworkMetrics returns network counters summed across interfaces, or
+// with ?iface=NAME the counters and rates of that interface alone.
+func (s *Server) handleNetworkMetrics(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("iface")
+	if name == "" {
+		handleMetric("network", s.metricsCollector.GetNetworkMetrics)(w, r)
 		return
 	}
 
-	status, err := s.dockerManager.GetStatus(r.Context())
+	m, err := s.metricsCollector.GetInterfaceMetrics(name)
+	if errors.Is(err, metrics.ErrInterfaceNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
 	if err != nil {
-		log.Printf("[ERROR] Failed to get Docker status: %v", err)
+		slog.Error("Failed to get interface metrics", "iface", name, "error", err)
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	log.Printf("[HANDLER] Docker status: %d containers, %d images", len(status.Containers), len(status.Images))
-	writeJSON(w, http.StatusOK, status)
+	if humanRequested(r) {
+		m.Humanize()
+	}
+	writeJSON(w, http.StatusOK, m)
 }
 
-// handleContainerStart handles starting a Docker container.
-func (s *Server) handleContainerStart(w http.ResponseWriter, r *http.Request) {
-	if s.dockerManager == nil {
-		writeError(w, http.StatusServiceUnavailable, "Docker not available")
+// handleProcess returns details of a single process.
+func (s *Server) handleProcess(w http.ResponseWriter, r *http.Request) {
+	pid, err := strconv.ParseInt(mux.Vars(r)["pid"], 10, 32)
+	if err != nil || pid <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid pid")
 		return
 	}
 
-	vars := mux.Vars(r)
-	containerID := vars["id"]
+	info, err := s.metricsCollector.GetProcess(r.Context(), int32(pid))
+	if err != nil {
+		writeProcessError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, info)
+}
 
-	if err := s.dockerManager.StartContainer(r.Context(), containerID); err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+// handleProcessesByName returns the processes named ?name= with their totals.
+func (s *Server) handleProcessesByName(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "name parameter required")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]string{"status": "started"})
+	group, err := s.metricsCollector.GetProcessesByName(r.Context(), name)
+	if err != nil {
+		writeProcessError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, group)
 }
 
-// handleContainerStop handles stopping a Docker container.
-func (s *Server) handleContainerStop(w http.ResponseWriter, r *http.Request) {
-	if s.dockerManager == nil {
-		writeError(w, http.StatusServiceUnavailable, "Docker not available")
+// handleSignalProcess sends an allowed signal to a process. The equivalent
+// kill command is checked against the exec policy first.
+func (s *Server) handleSignalProcess(w http.ResponseWriter, r *http.Request) {
+	pid, err := strconv.ParseInt(mux.Vars(r)["pid"], 10, 32)
+	if err != nil || pid <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid pid")
+		return
+	}
+	var req SignalRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	sig, name, err := metrics.ParseSignal(req.Signal)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	vars := mux.Vars(r)
-	containerID := vars["id"]
+	command := fmt.Sprintf("kill -%s %d", strings.TrimPrefix(name, "SIG"), pid)
+	if err := s.execPolicy.Load().Check(command); err != nil {
+		slog.Warn("Rejected process signal", "command", command, "client", clientSubject(r), "requestId", requestID(r), "error", err)
+		writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	slog.Info("Signaling process", "pid", pid, "signal", name, "client", clientSubject(r), "requestId", requestID(r))
+	if err := metrics.SignalProcess(r.Context(), int32(pid), sig); err != nil {
+		slog.Warn("Failed to signal process", "pid", pid, "signal", name, "requestId", requestID(r), "error", err)
+		writeProcessError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, SignalResponse{PID: int32(pid), Signal: name})
+}
+
+// writeProcessError writes a process lookup or signal error, using 404 when
+// no process matched and 403 when access was denied.
+func writeProcessError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, metrics.ErrProcessNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, metrics.ErrProtectedProcess), errors.Is(err, os.ErrPermission):
+		writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	slog.Error("Failed to read process", "error", err)
+	writeError(w, http.StatusInternalServerError, err.Error())
+}
 
-	if err := s.dockerManager.StopContainer(r.Context(), containerID); err != nil {
+// handleDocker handles the Docker status endpoint. ?limit= and ?offset= page
+// the container and image lists, which hold the first 100 of each by default;
+// the totals count them all.
+func (s *Server) handleDocker(w http.ResponseWriter, r *http.Request) {
+	slog.Debug("Docker status requested")
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	status, err := s.dockerStatus(r.Context(), limit, offset)
+	if err != nil {
+		slog.Error("Failed to get Docker status", "error", err)
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	writeJSON(w, http.StatusOK, status)
+}
 
-	writeJSON(w, http.StatusOK, map[string]string{"status": "stopped"})
+// dockerStatus returns the Docker status with its lists paged by limit and
+// offset, or an empty status with Installed unset when Docker isn't available.
+func (s *Server) dockerStatus(ctx context.Context, limit, offset int) (*docker.Status, error) {
+	dm := s.dockerManager.Load()
+	if dm == nil {
+		slog.Debug("Docker not available, returning empty status")
+		return &docker.Status{
+			Installed:  false,
+			Containers: []docker.Container{},
+			Images:     []docker.Image{},
+		}, nil
+	}
+
+	status, err := dm.GetStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slog.Debug("Docker status collected", "containers", status.TotalContainers, "images", status.TotalImages)
+	status.Containers = paginate(status.Containers, limit, offset)
+	status.Images = paginate(status.Images, limit, offset)
+	return status, nil
 }
 
-// handleUpdates handles the updates endpoint.
-func (s *Server) handleUpdates(w http.ResponseWriter, r *http.Request) {
-	log.Println("[HANDLER] Updates check requested")
-	pkgs, err := s.updatesManager.GetUpdates(r.Context())
+// DashboardResponse combines system info, metrics and Docker status. A
+// section that failed is omitted and its error reported in Errors.
+type DashboardResponse struct {
+	Instance  string              `json:"instance"`
+	System    *metrics.SystemInfo `json:"system,omitempty"`
+	Metrics   *metrics.Metrics    `json:"metrics,omitempty"`
+	Docker    *docker.Status      `json:"docker,omitempty"`
+	Timestamp int64               `json:"timestamp"`
+
+	// Errors maps each section that failed to its error
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// handleDashboard returns /api/system, /api/metrics and /api/docker in one
+// response, collected concurrently, with the Docker lists capped at their
+// default page size. It supports ?human=true like /api/metrics.
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	var resp DashboardResponse
+	var mu sync.Mutex
+	fail := func(section string, err error) {
+		slog.Error("Failed to collect dashboard section", "section", section, "error", err)
+		mu.Lock()
+		defer mu.Unlock()
+		if resp.Errors == nil {
+			resp.Errors = make(map[string]string)
+		}
+		resp.Errors[section] = err.Error()
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		if info, err := s.metricsCollector.GetSystemInfo(); err != nil {
+			fail("system", err)
+		} else {
+			resp.System = info
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if m, err := s.cachedMetrics(); err != nil {
+			fail("metrics", err)
+		} else {
+			if humanRequested(r) {
+				m.Humanize()
+			}
+			resp.Metrics = m
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if status, err := s.dockerStatus(r.Context(), defaultPageLimit, 0); err != nil {
+			fail("docker", err)
+		} else {
+			resp.Docker = status
+		}
+	}()
+	wg.Wait()
+
+	resp.Instance = s.instance
+	resp.Timestamp = time.Now().UnixMilli()
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleDockerInfo returns the Docker daemon version and system information.
+func (s *Server) handleDockerInfo(w http.ResponseWriter, r *http.Request) {
+	dm := s.dockerManager.Load()
+	if dm == nil {
+		writeError(w, http.StatusServiceUnavailable, "Docker not available")
+		return
+	}
+
+	info, err := dm.GetInfo(r.Context())
 	if err != nil {
-		log.Printf("[ERROR] Failed to get updates: %v", err)
+		slog.Error("Failed to get Docker info", "error", err)
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	log.Printf("[HANDLER] Found %d available updates", len(pkgs))
-	writeJSON(w, http.StatusOK, pkgs)
+	writeJSON(w, http.StatusOK, info)
 }
 
-// handleApplyUpdate handles applying a single package update.
-func (s *Server) handleApplyUpdate(w http.ResponseWriter, r *http.Request) {
-	var req ApplyUpdateRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body")
+// Pagination bounds for list endpoints.
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+// parsePagination reads ?limit= and ?offset= from the query.
+func parsePagination(r *http.Request) (limit, offset int, err error) {
+	query := r.URL.Query()
+
+	limit = defaultPageLimit
+	if v := query.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return 0, 0, errors.New("offset must be a non-negative integer")
+		}
+	}
+	return limit, offset, nil
+}
+
+// paginate returns the window of items selected by limit and offset.
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+	end := offset + limit
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[offset:end]
+}
+
+// handleContainers lists containers, optionally filtered by state and labels.
+// Query: ?state=running|exited|...|all, repeatable ?label=key or ?label=key=value,
+// and ?limit=/?offset= for paging.
+func (s *Server) handleContainers(w http.ResponseWriter, r *http.Request) {
+	dm := s.dockerManager.Load()
+	if dm == nil {
+		writeError(w, http.StatusServiceUnavailable, "Docker not available")
 		return
 	}
 
-	if req.Package == "" {
-		writeError(w, http.StatusBadRequest, "package name required")
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	result, err := s.updatesManager.ApplyUpdate(r.Context(), req.Package)
+	query := r.URL.Query()
+	filter := docker.ContainerFilter{
+		State:   query.Get("state"),
+		Labels:  query["label"],
+		Project: query.Get("project"),
+	}
+	if err := filter.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	containers, err := dm.ListContainers(r.Context(), filter)
 	if err != nil {
+		slog.Error("Failed to list containers", "error", err)
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	writeJSON(w, http.StatusOK, result)
+	writeJSON(w, http.StatusOK, ContainerPage{
+		Containers: paginate(containers, limit, offset),
+		Total:      len(containers),
+		Limit:      limit,
+		Offset:     offset,
+	})
 }
 
-// handleApplyAllUpdates handles applying all available updates.
-func (s *Server) handleApplyAllUpdates(w http.ResponseWriter, r *http.Request) {
-	result, err := s.updatesManager.ApplyAllUpdates(r.Context())
+// handleComposeProjects lists containers grouped by Docker Compose project.
+func (s *Server) handleComposeProjects(w http.ResponseWriter, r *http.Request) {
+	dm := s.dockerManager.Load()
+	if dm == nil {
+		writeError(w, http.StatusServiceUnavailable, "Docker not available")
+		return
+	}
+
+	containers, err := dm.ListContainers(r.Context(), docker.ContainerFilter{})
 	if err != nil {
+		slog.Error("Failed to list containers", "error", err)
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	writeJSON(w, http.StatusOK, result)
+	writeJSON(w, http.StatusOK, docker.GroupByProject(containers))
 }
 
-// handleExec handles command execution.
-func (s *Server) handleExec(w http.ResponseWriter, r *http.Request) {
-	var req ExecRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body")
+// ComposeActionResponse is the response for a Compose project action.
+type ComposeActionResponse struct {
+	Project string                         `json:"project"`
+	Action  string                         `json:"action"`
+	OK      bool                           `json:"ok"` // the action succeeded on every container
+	Results []docker.ContainerActionResult `json:"results"`
+}
+
+// handleComposeAction starts, stops or restarts every container in a Compose project.
+func (s *Server) handleComposeAction(w http.ResponseWriter, r *http.Request) {
+	dm := s.dockerManager.Load()
+	if dm == nil {
+		writeError(w, http.StatusServiceUnavailable, "Docker not available")
 		return
 	}
 
-	if req.Command == "" {
-		writeError(w, http.StatusBadRequest, "command required")
+	vars := mux.Vars(r)
+	project, action := vars["name"], vars["action"]
+	if project == docker.UngroupedProject {
+		writeError(w, http.StatusBadRequest, "ungrouped containers are not a Compose project")
 		return
 	}
 
-	result, err := updates.ExecuteCommand(r.Context(), req.Command)
+	results, err := dm.ProjectAction(r.Context(), project, action)
 	if err != nil {
+		slog.Error("Compose project action failed", "project", project, "action", action, "error", err)
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	writeJSON(w, http.StatusOK, result)
+	if len(results) == 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("compose project %q not found", project))
+		return
+	}
+
+	resp := ComposeActionResponse{Project: project, Action: action, OK: true, Results: results}
+	for _, res := range results {
+		if !res.Success {
+			resp.OK = false
+		}
+	}
+	slog.Info("Compose project action", "project", project, "action", action, "containers", len(results), "ok", resp.OK, "requestId", requestID(r))
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ContainerBatchRequest is the request body for a batch container action.
+type ContainerBatchRequest struct {
+	Action string   `json:"action"` // start, stop or restart
+	IDs    []string `json:"ids"`
+}
+
+// ContainerBatchResponse is the response for a batch container action.
+type ContainerBatchResponse struct {
+	Action  string                         `json:"action"`
+	OK      bool                           `json:"ok"` // the action succeeded on every container
+	Results []docker.ContainerActionResult `json:"results"`
+}
+
+// handleContainerBatch starts, stops or restarts several containers at once,
+// reporting the outcome for each rather than failing on the first error.
+func (s *Server) handleContainerBatch(w http.ResponseWriter, r *http.Request) {
+	dm := s.dockerManager.Load()
+	if dm == nil {
+		writeError(w, http.StatusServiceUnavailable, "Docker not available")
+		return
+	}
+
+	var req ContainerBatchRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	switch {
+	case len(req.IDs) == 0:
+		writeError(w, http.StatusBadRequest, "ids is required")
+		return
+	case len(req.IDs) > docker.MaxBatchContainers:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("at most %d containers per batch", docker.MaxBatchContainers))
+		return
+	}
+	ids := make([]string, 0, len(req.IDs))
+	for _, id := range req.IDs {
+		if !validContainerID(id) {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid container ID %q", id))
+			return
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	results, err := dm.BatchAction(r.Context(), req.Action, ids)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp := ContainerBatchResponse{Action: req.Action, OK: true, Results: results}
+	for _, res := range results {
+		if !res.Success {
+			resp.OK = false
+		}
+	}
+	slog.Info("Batch container action", "action", req.Action, "containers", len(results), "ok", resp.OK, "client", clientSubject(r), "requestId", requestID(r))
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleImages lists images with paging and optional ?sort=size|created.
+// Responses carry an ETag so polling clients can revalidate cheaply.
+func (s *Server) handleImages(w http.ResponseWriter, r *http.Request) {
+	dm := s.dockerManager.Load()
+	if dm == nil {
+		writeError(w, http.StatusServiceUnavailable, "Docker not available")
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	images, err := dm.ListImages(r.Context())
+	if err != nil {
+		slog.Error("Failed to list images", "error", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := docker.SortImages(images, r.URL.Query().Get("sort")); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSONWithETag(w, r, ImagePage{
+		Images: paginate(images, limit, offset),
+		Total:  len(images),
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// handleContainerInspect returns the daemon's full inspect JSON for a
+// container, unmodified, for fields ContainerDetails doesn't cover.
+func (s *Server) handleContainerInspect(w http.ResponseWriter, r *http.Request) {
+	dm := s.dockerManager.Load()
+	if dm == nil {
+		writeError(w, http.StatusServiceUnavailable, "Docker not available")
+		return
+	}
+
+	containerID := mux.Vars(r)["id"]
+	if !validContainerID(containerID) {
+		writeError(w, http.StatusBadRequest, "invalid container ID")
+		return
+	}
+
+	raw, err := dm.InspectContainerRaw(r.Context(), containerID)
+	if err != nil {
+		writeDockerError(w, containerID, err)
+		return
+	}
+	writeRawJSON(w, raw)
+}
+
+// handleContainerStats returns a container's current CPU and memory usage.
+func (s *Server) handleContainerStats(w http.ResponseWriter, r *http.Request) {
+	dm := s.dockerManager.Load()
+	if dm == nil {
+		writeError(w, http.StatusServiceUnavailable, "Docker not available")
+		return
+	}
+
+	containerID := mux.Vars(r)["id"]
+	if !validContainerID(containerID) {
+		writeError(w, http.StatusBadRequest, "invalid container ID")
+		return
+	}
+
+	stats, err := dm.GetContainerStats(r.Context(), containerID)
+	if err != nil {
+		writeDockerError(w, containerID, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// handleImageInspect returns the daemon's full inspect JSON for an image,
+// unmodified.
+func (s *Server) handleImageInspect(w http.ResponseWriter, r *http.Request) {
+	dm := s.dockerManager.Load()
+	if dm == nil {
+		writeError(w, http.StatusServiceUnavailable, "Docker not available")
+		return
+	}
+
+	image := mux.Vars(r)["image"]
+	raw, err := dm.InspectImageRaw(r.Context(), image)
+	if err != nil {
+		switch {
+		case errors.Is(err, docker.ErrInvalidReference):
+			writeError(w, http.StatusBadRequest, err.Error())
+		case docker.IsNotFound(err):
+			writeError(w, http.StatusNotFound, fmt.Sprintf("image %q not found", image))
+		default:
+			writeError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	writeRawJSON(w, raw)
+}
+
+// writeRawJSON writes an already encoded JSON body with a 200 status.
+func writeRawJSON(w http.ResponseWriter, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// ImageTagRequest is the request body for tagging an image.
+type ImageTagRequest struct {
+	Source string `json:"source"` // image ID or name
+	Target string `json:"target"` // new name, e.g. registry.local/app:v2
+}
+
+// handleImageTag adds a tag to an existing image.
+func (s *Server) handleImageTag(w http.ResponseWriter, r *http.Request) {
+	dm := s.dockerManager.Load()
+	if dm == nil {
+		writeError(w, http.StatusServiceUnavailable, "Docker not available")
+		return
+	}
+
+	var req ImageTagRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Source == "" || req.Target == "" {
+		writeError(w, http.StatusBadRequest, "source and target are required")
+		return
+	}
+
+	if err := dm.TagImage(r.Context(), req.Source, req.Target); err != nil {
+		switch {
+		case errors.Is(err, docker.ErrInvalidReference):
+			writeError(w, http.StatusBadRequest, err.Error())
+		case docker.IsNotFound(err):
+			writeError(w, http.StatusNotFound, fmt.Sprintf("image %q not found", req.Source))
+		default:
+			writeError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	slog.Info("Tagged image", "source", req.Source, "target", req.Target, "client", clientSubject(r), "requestId", requestID(r))
+	writeJSON(w, http.StatusOK, map[string]string{"source": req.Source, "target": req.Target})
+}
+
+// containerIDPattern matches full or short hex container IDs as well as
+// container names, which Docker restricts to [a-zA-Z0-9][a-zA-Z0-9_.-]*.
+var containerIDPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,254}$`)
+
+// validContainerID reports whether id looks like a container ID or name.
+func validContainerID(id string) bool {
+	return containerIDPattern.MatchString(id)
+}
+
+// writeDockerError writes 404 for containers the daemon doesn't know about
+// and 500 for any other Docker failure.
+func writeDockerError(w http.ResponseWriter, containerID string, err error) {
+	if docker.IsNotFound(err) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("container %q not found", containerID))
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err.Error())
+}
+
+// handleContainerStart handles starting a Docker container.
+func (s *Server) handleContainerStart(w http.ResponseWriter, r *http.Request) {
+	dm := s.dockerManager.Load()
+	if dm == nil {
+		writeError(w, http.StatusServiceUnavailable, "Docker not available")
+		return
+	}
+
+	vars := mux.Vars(r)
+	containerID := vars["id"]
+	if !validContainerID(containerID) {
+		writeError(w, http.StatusBadRequest, "invalid container ID")
+		return
+	}
+
+	if err := dm.StartContainer(r.Context(), containerID); err != nil {
+		writeDockerError(w, containerID, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "started"})
+}
+
+// handleContainerStop handles stopping a Docker container.
+func (s *Server) handleContainerStop(w http.ResponseWriter, r *http.Request) {
+	dm := s.dockerManager.Load()
+	if dm == nil {
+		writeError(w, http.StatusServiceUnavailable, "Docker not available")
+		return
+	}
+
+	vars := mux.Vars(r)
+	containerID := vars["id"]
+	if !validContainerID(containerID) {
+		writeError(w, http.StatusBadRequest, "invalid container ID")
+		return
+	}
+
+	if err := dm.StopContainer(r.Context(), containerID); err != nil {
+		writeDockerError(w, containerID, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "stopped"})
+}
+
+// parseLogWindow validates since/until filters and stores them in opts.
+func parseLogWindow(opts *docker.LogsOptions, since, until string) error {
+	now := time.Now()
+
+	var err error
+	if opts.Since, err = docker.ParseLogTime(since, now); err != nil {
+		return fmt.Errorf("since: %w", err)
+	}
+	if opts.Until, err = docker.ParseLogTime(until, now); err != nil {
+		return fmt.Errorf("until: %w", err)
+	}
+	return nil
+}
+
+// countingWriter counts bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// handleContainerLogs streams a container's logs directly to the response,
+// capped at the configured maximum size. With download=true the response is
+// marked as a file attachment. grep keeps only matching lines; it is a
+// substring unless regex=true, and ignoreCase=true and invert=true work like
+// grep -i and grep -v.
+func (s *Server) handleContainerLogs(w http.ResponseWriter, r *http.Request) {
+	dm := s.dockerManager.Load()
+	if dm == nil {
+		writeError(w, http.StatusServiceUnavailable, "Docker not available")
+		return
+	}
+
+	vars := mux.Vars(r)
+	containerID := vars["id"]
+	if !validContainerID(containerID) {
+		writeError(w, http.StatusBadRequest, "invalid container ID")
+		return
+	}
+
+	query := r.URL.Query()
+	tail := query.Get("tail")
+	if tail == "" {
+		tail = "all"
+	}
+	opts := docker.LogsOptions{
+		Tail:       tail,
+		Timestamps: true,
+	}
+	if err := parseLogWindow(&opts, query.Get("since"), query.Get("until")); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if grep := query.Get("grep"); grep != "" {
+		filter, err := docker.NewLogFilter(grep, query.Get("regex") == "true", query.Get("ignoreCase") == "true", query.Get("invert") == "true")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		opts.Filter = filter
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if query.Get("download") == "true" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.log"`, containerID))
+	}
+
+	cw := &countingWriter{w: w}
+	truncated, err := dm.CopyLogs(r.Context(), containerID, opts, cw, s.config.MaxLogBytes)
+	if err != nil {
+		if cw.n == 0 {
+			w.Header().Del("Content-Disposition")
+			writeDockerError(w, containerID, err)
+			return
+		}
+		slog.Warn("Container log download interrupted", "container", containerID, "bytes", cw.n, "error", err)
+		return
+	}
+	if truncated {
+		fmt.Fprintf(w, "\n[log output truncated at %d bytes]\n", s.config.MaxLogBytes)
+	}
+	slog.Debug("Container logs written", "container", containerID, "bytes", cw.n, "truncated", truncated)
+}
+
+// handleContainerKill sends a signal (default SIGKILL) to a Docker container.
+func (s *Server) handleContainerKill(w http.ResponseWriter, r *http.Request) {
+	dm := s.dockerManager.Load()
+	if dm == nil {
+		writeError(w, http.StatusServiceUnavailable, "Docker not available")
+		return
+	}
+
+	vars := mux.Vars(r)
+	containerID := vars["id"]
+	if !validContainerID(containerID) {
+		writeError(w, http.StatusBadRequest, "invalid container ID")
+		return
+	}
+
+	signal, err := docker.NormalizeSignal(r.URL.Query().Get("signal"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := dm.KillContainer(r.Context(), containerID, signal); err != nil {
+		writeDockerError(w, containerID, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "killed", "signal": signal})
+}
+
+// handleUpdates handles the updates endpoint.
+// Results are cached; ?refresh=true forces a new check. The X-Checked-At
+// header gives the time of the check the results came from.
+func (s *Server) handleUpdates(w http.ResponseWriter, r *http.Request) {
+	slog.Debug("Updates check requested")
+	refresh := r.URL.Query().Get("refresh") == "true"
+	pkgs, checkedAt, err := s.updatesManager.CachedUpdates(r.Context(), refresh)
+	if err != nil {
+		slog.Error("Failed to get updates", "error", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	slog.Info("Found available updates", "count", len(pkgs))
+	w.Header().Set("X-Checked-At", checkedAt.UTC().Format(time.RFC3339))
+	writeJSON(w, http.StatusOK, pkgs)
+}
+
+// handleUpdatesStatus reports on the background package index refresher.
+func (s *Server) handleUpdatesStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.updatesManager.RefreshStatus())
+}
+
+// handleHeldPackages lists packages pinned against upgrades.
+func (s *Server) handleHeldPackages(w http.ResponseWriter, r *http.Request) {
+	held, err := s.updatesManager.GetHeldPackages(r.Context())
+	if err != nil {
+		slog.Error("Failed to list held packages", "error", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if held == nil {
+		held = []string{}
+	}
+	writeJSON(w, http.StatusOK, held)
+}
+
+// handleApplyUpdate handles applying a single package update.
+func (s *Server) handleApplyUpdate(w http.ResponseWriter, r *http.Request) {
+	var req ApplyUpdateRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if req.Package == "" {
+		writeError(w, http.StatusBadRequest, "package name required")
+		return
+	}
+
+	result, err := s.updatesManager.ApplyUpdate(r.Context(), req.Package)
+	writeCommandResult(w, result, err)
+}
+
+// handleSearchPackages searches the package index for ?q=.
+func (s *Server) handleSearchPackages(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "q parameter required")
+		return
+	}
+
+	pkgs, err := s.updatesManager.SearchPackages(r.Context(), query)
+	if errors.Is(err, updates.ErrInvalidQuery) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		slog.Error("Package search failed", "query", query, "error", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, pkgs)
+}
+
+// handleInstallPackage installs a package by name.
+func (s *Server) handleInstallPackage(w http.ResponseWriter, r *http.Request) {
+	var req ApplyUpdateRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Package == "" {
+		writeError(w, http.StatusBadRequest, "package name required")
+		return
+	}
+
+	result, err := s.updatesManager.InstallPackage(r.Context(), req.Package)
+	writeCommandResult(w, result, err)
+}
+
+// handleRemovePackage removes a package by name.
+func (s *Server) handleRemovePackage(w http.ResponseWriter, r *http.Request) {
+	var req ApplyUpdateRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Package == "" {
+		writeError(w, http.StatusBadRequest, "package name required")
+		return
+	}
+
+	result, err := s.updatesManager.RemovePackage(r.Context(), req.Package)
+	writeCommandResult(w, result, err)
+}
+
+// UpdatePreviewResponse is the response for a dry-run apply-all.
+type UpdatePreviewResponse struct {
+	DryRun  bool                    `json:"dryRun"`
+	Updates []updates.PackageUpdate `json:"updates"`
+	Result  *updates.CommandResult  `json:"result"`
+}
+
+// handleApplyAllUpdates handles applying all available updates. With
+// ?dryRun=true it only reports what would be upgraded.
+func (s *Server) handleApplyAllUpdates(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("dryRun") == "true" {
+		pkgs, result, err := s.updatesManager.SimulateAllUpdates(r.Context())
+		if err != nil {
+			writeCommandResult(w, result, err)
+			return
+		}
+		if pkgs == nil {
+			pkgs = []updates.PackageUpdate{}
+		}
+		writeJSON(w, http.StatusOK, UpdatePreviewResponse{DryRun: true, Updates: pkgs, Result: result})
+		return
+	}
+
+	result, err := s.updatesManager.ApplyAllUpdates(r.Context())
+	writeCommandResult(w, result, err)
+}
+
+// handleExec handles command execution.
+func (s *Server) handleExec(w http.ResponseWriter, r *http.Request) {
+	var req ExecRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if req.Command == "" {
+		writeError(w, http.StatusBadRequest, "command required")
+		return
+	}
+
+	if err := s.execPolicy.Load().Check(req.Command); err != nil {
+		slog.Warn("Rejected exec request", "command", req.Command, "client", clientSubject(r), "requestId", requestID(r), "error", err)
+		writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	opts := updates.ExecOptions{Dir: req.Dir, Env: req.Env}
+	if err := s.checkExecOptions(opts); err != nil {
+		slog.Warn("Rejected exec request", "command", req.Command, "dir", req.Dir, "client", clientSubject(r), "requestId", requestID(r), "error", err)
+		writeError(w, execOptionsStatus(err), err.Error())
+		return
+	}
+
+	slog.Info("Executing command", "command", req.Command, "dir", req.Dir, "client", clientSubject(r), "requestId", requestID(r))
+
+	result, err := s.updatesManager.ExecuteCommand(r.Context(), req.Command, opts)
+	writeCommandResult(w, result, err)
+}
+
+// checkExecOptions validates an exec request's environment and checks it and
+// the working directory against the exec policy.
+func (s *Server) checkExecOptions(opts updates.ExecOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	policy := s.execPolicy.Load()
+	for name := range opts.Env {
+		if err := policy.CheckEnv(name); err != nil {
+			return err
+		}
+	}
+	if opts.Dir == "" {
+		return nil
+	}
+	return policy.CheckDir(opts.Dir)
+}
+
+// execOptionsStatus maps a checkExecOptions error to an HTTP status.
+func execOptionsStatus(err error) int {
+	if errors.Is(err, execpolicy.ErrDirNotAllowed) || errors.Is(err, execpolicy.ErrEnvNotAllowed) {
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+// errHostControlDisabled is reported when host control requests aren't enabled.
+var errHostControlDisabled = errors.New("host control is disabled; start the agent with --host-control")
+
+// handleHostAction schedules a reboot or shutdown after the configured delay.
+// It requires --host-control, and the shutdown command is checked against
+// the exec policy.
+func (s *Server) handleHostAction(w http.ResponseWriter, r *http.Request) {
+	if !s.config.HostControl {
+		writeError(w, http.StatusForbidden, errHostControlDisabled.Error())
+		return
+	}
+
+	action := mux.Vars(r)["action"]
+	args, err := host.Command(action, s.config.HostShutdownDelay)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	command := strings.Join(args, " ")
+	if err := s.execPolicy.Load().Check(command); err != nil {
+		slog.Warn("Rejected host action", "command", command, "client", clientSubject(r), "requestId", requestID(r), "error", err)
+		writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	slog.Warn("Scheduling host action", "action", action, "delay", s.config.HostShutdownDelay, "client", clientSubject(r), "requestId", requestID(r))
+	schedule, err := host.ScheduleAction(r.Context(), action, s.config.HostShutdownDelay)
+	if err != nil {
+		slog.Error("Failed to schedule host action", "action", action, "requestId", requestID(r), "error", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, schedule)
+}
+
+// handleHostCancel aborts a scheduled reboot or shutdown.
+func (s *Server) handleHostCancel(w http.ResponseWriter, r *http.Request) {
+	if !s.config.HostControl {
+		writeError(w, http.StatusForbidden, errHostControlDisabled.Error())
+		return
+	}
+
+	command := strings.Join(host.CancelCommand(), " ")
+	if err := s.execPolicy.Load().Check(command); err != nil {
+		slog.Warn("Rejected host action", "command", command, "client", clientSubject(r), "requestId", requestID(r), "error", err)
+		writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	slog.Info("Canceling scheduled host action", "client", clientSubject(r), "requestId", requestID(r))
+	if err := host.Cancel(r.Context()); err != nil {
+		slog.Error("Failed to cancel host action", "requestId", requestID(r), "error", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"canceled": true})
+}
+
+// OOMEventsResponse is the response for the OOM events endpoint.
+type OOMEventsResponse struct {
+	Events []oom.Event `json:"events"`
+	// Errors maps each source that could not be read to its error
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// handleOOMEvents returns recent OOM kills from Docker events and the kernel log, newest first.
+func (s *Server) handleOOMEvents(w http.ResponseWriter, r *http.Request) {
+	resp := OOMEventsResponse{Events: s.oomEvents.Events()}
+
+	kernel, err := oom.KernelEvents(r.Context())
+	if err != nil {
+		slog.Debug("Kernel OOM events unavailable", "error", err)
+		resp.Errors = map[string]string{oom.SourceKernel: err.Error()}
+	}
+	resp.Events = append(resp.Events, kernel...)
+
+	sort.SliceStable(resp.Events, func(i, j int) bool {
+		return resp.Events[i].Time > resp.Events[j].Time
+	})
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// AlertsResponse is the response for the alerts endpoint.
+type AlertsResponse struct {
+	Rules  []string       `json:"rules"`
+	Alerts []alerts.Alert `json:"alerts"`
+}
+
+// handleAlerts returns the configured rules and the alerts that are pending or firing.
+func (s *Server) handleAlerts(w http.ResponseWriter, r *http.Request) {
+	resp := AlertsResponse{Rules: []string{}, Alerts: s.alerts.Alerts()}
+	for _, rule := range s.alerts.Rules() {
+		resp.Rules = append(resp.Rules, rule.Expr)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ServiceActionResponse is the response for a service start, stop or restart.
+type ServiceActionResponse struct {
+	Unit   string `json:"unit"`
+	Action string `json:"action"`
+}
+
+// writeServiceError maps services errors to HTTP statuses.
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrNotAvailable):
+		writeError(w, http.StatusNotImplemented, err.Error())
+	case errors.Is(err, services.ErrInvalidUnit), errors.Is(err, services.ErrInvalidAction),
+		errors.Is(err, services.ErrInvalidLines):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// handleServices lists systemd service units.
+func (s *Server) handleServices(w http.ResponseWriter, r *http.Request) {
+	list, err := s.servicesManager.List(r.Context())
+	if err != nil {
+		slog.Debug("Failed to list services", "error", err)
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// handleServiceAction starts, stops or restarts a service.
+// The systemctl command is checked against the exec policy first.
+func (s *Server) handleServiceAction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	action := vars["action"]
+
+	unit, err := services.NormalizeUnit(vars["name"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	command, err := services.ControlCommand(action, unit)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	if err := s.execPolicy.Load().Check(command); err != nil {
+		slog.Warn("Rejected service action", "command", command, "client", clientSubject(r), "requestId", requestID(r), "error", err)
+		writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	slog.Info("Running service action", "unit", unit, "action", action, "client", clientSubject(r), "requestId", requestID(r))
+	if err := s.servicesManager.Control(r.Context(), action, unit); err != nil {
+		slog.Error("Service action failed", "unit", unit, "action", action, "requestId", requestID(r), "error", err)
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ServiceActionResponse{Unit: unit, Action: action})
+}
+
+// parseJournalOptions reads the unit, since and lines query parameters.
+func parseJournalOptions(query url.Values) (services.JournalOptions, error) {
+	opts := services.JournalOptions{Unit: query.Get("unit")}
+	if v := query.Get("since"); v != "" {
+		t, err := parseSince(v, time.Now())
+		if err != nil {
+			return opts, err
+		}
+		opts.Since = t
+	}
+	if v := query.Get("lines"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return opts, services.ErrInvalidLines
+		}
+		opts.Lines = n
+	}
+	return opts, nil
+}
+
+// handleJournal returns recent journald entries, optionally for one unit.
+func (s *Server) handleJournal(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseJournalOptions(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entries, err := s.servicesManager.Journal(r.Context(), opts)
+	if err != nil {
+		slog.Debug("Failed to read journal", "unit", opts.Unit, "error", err)
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
 }
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aniket/servertui/agent/internal/docker"
+)
+
+// ContainerLogLine is a log line from a multi-container stream, tagged with
+// the container it came from.
+type ContainerLogLine struct {
+	Container string `json:"container"` // ID or name as given by the client
+	Stream    string `json:"stream"`
+	Line      string `json:"line"`
+}
+
+// multiLogStream follows the logs of a changing set of containers, merging
+// them into a single stream of logLine messages.
+type multiLogStream struct {
+	s    *Server
+	conn *wsConn
+	opts docker.LogsOptions
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	streams map[string]*logStream
+	wg      sync.WaitGroup
+
+	out     chan ContainerLogLine
+	dropped atomic.Int64
+	done    chan struct{} // closed when the sender exits
+}
+
+// newMultiLogStream starts an empty multi-container stream on conn.
+func (s *Server) newMultiLogStream(conn *wsConn, opts docker.LogsOptions) *multiLogStream {
+	ctx, cancel := context.WithCancel(s.shutdownCtx)
+	ms := &multiLogStream{
+		s:       s,
+		conn:    conn,
+		opts:    opts,
+		ctx:     ctx,
+		cancel:  cancel,
+		streams: make(map[string]*logStream),
+		out:     make(chan ContainerLogLine, logBufferSize),
+		done:    make(chan struct{}),
+	}
+	go ms.send()
+	return ms
+}
+
+// add starts following containerID. It is a no-op if already followed.
+func (ms *multiLogStream) add(containerID string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if _, ok := ms.streams[containerID]; ok || ms.ctx.Err() != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ms.ctx)
+	ls := &logStream{containerID: containerID, cancel: cancel, done: make(chan struct{})}
+	ms.streams[containerID] = ls
+
+	ms.wg.Add(1)
+	go func() {
+		defer ms.wg.Done()
+		defer close(ls.done)
+		ms.follow(ctx, containerID)
+
+		ms.mu.Lock()
+		if ms.streams[containerID] == ls {
+			delete(ms.streams, containerID)
+		}
+		ms.mu.Unlock()
+	}()
+}
+
+// remove stops following containerID and reports whether it was followed.
+func (ms *multiLogStream) remove(containerID string) bool {
+	ms.mu.Lock()
+	ls, ok := ms.streams[containerID]
+	delete(ms.streams, containerID)
+	ms.mu.Unlock()
+
+	if ok {
+		ls.stop()
+	}
+	return ok
+}
+
+// stop ends every container's stream and waits for them and the sender to exit.
+func (ms *multiLogStream) stop() {
+	ms.cancel()
+	ms.wg.Wait()
+	<-ms.done
+}
+
+// follow relays one container's logs into the shared channel, dropping lines
+// when it is full, until ctx is canceled or the container's log stream ends.
+func (ms *multiLogStream) follow(ctx context.Context, containerID string) {
+	dm := ms.s.dockerManager.Load()
+	if dm == nil {
+		return
+	}
+
+	raw := make(chan docker.LogLine)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(raw)
+		errc <- dm.StreamLogs(ctx, containerID, ms.opts, raw)
+	}()
+
+	for line := range raw {
+		select {
+		case ms.out <- ContainerLogLine{Container: containerID, Stream: line.Stream, Line: line.Line}:
+		default:
+			ms.dropped.Add(1)
+		}
+	}
+
+	err := <-errc
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		slog.Warn("Log streaming error", "container", containerID, "error", err)
+	}
+	ms.s.sendWSMessage(ms.conn, "logStreamEnded", map[string]string{"containerId": containerID})
+}
+
+// send writes merged log lines to the client until the stream is stopped or
+// a write fails.
+func (ms *multiLogStream) send() {
+	defer close(ms.done)
+	for {
+		var line ContainerLogLine
+		select {
+		case <-ms.ctx.Done():
+			return
+		case line = <-ms.out:
+		}
+
+		if n := ms.dropped.Swap(0); n > 0 {
+			slog.Debug("Dropped log lines for slow client", "count", n)
+			if err := ms.s.sendWSMessage(ms.conn, "logLinesDropped", map[string]int64{"count": n}); err != nil {
+				ms.cancel()
+				return
+			}
+		}
+		if err := ms.s.sendWSMessage(ms.conn, "logLine", line); err != nil {
+			slog.Warn("Failed to send log line", "error", err)
+			ms.cancel()
+			return
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+)
+
+// newPprofServer creates a server exposing net/http/pprof under /debug/pprof.
+// It is kept apart from the API so profiles are never reachable through the
+// main listener.
+func newPprofServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return &http.Server{Addr: addr, Handler: mux}
+}
+
+// servePprof runs srv until it is closed. Failing to listen is logged but
+// doesn't stop the agent.
+func servePprof(srv *http.Server) {
+	if host, _, err := net.SplitHostPort(srv.Addr); err == nil {
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			slog.Warn("pprof server is not bound to localhost", "addr", srv.Addr)
+		}
+	}
+
+	slog.Info("Starting pprof server", "addr", srv.Addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("pprof server failed", "error", err)
+	}
+}
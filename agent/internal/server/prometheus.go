@@ -0,0 +1,179 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/aniket/servertui/agent/internal/docker"
+)
+
+// promStatsWorkers bounds how many containers are sampled at once per scrape.
+const promStatsWorkers = 4
+
+// promLabelEscaper escapes label values per the exposition format.
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// promWriter writes metrics in the Prometheus text exposition format.
+type promWriter struct {
+	w io.Writer
+}
+
+// header writes the HELP and TYPE lines for a metric family.
+func (p *promWriter) header(name, help, kind string) {
+	fmt.Fprintf(p.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// sample writes a single sample. labels are alternating name/value pairs.
+func (p *promWriter) sample(name string, value float64, labels ...string) {
+	if len(labels) == 0 {
+		fmt.Fprintf(p.w, "%s %g\n", name, value)
+		return
+	}
+
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, labels[i], promLabelEscaper.Replace(labels[i+1])))
+	}
+	fmt.Fprintf(p.w, "%s{%s} %g\n", name, strings.Join(pairs, ","), value)
+}
+
+// gauge writes a complete single-sample gauge family.
+func (p *promWriter) gauge(name, help string, value float64, labels ...string) {
+	p.header(name, help, "gauge")
+	p.sample(name, value, labels...)
+}
+
+// counter writes a complete single-sample counter family.
+func (p *promWriter) counter(name, help string, value float64, labels ...string) {
+	p.header(name, help, "counter")
+	p.sample(name, value, labels...)
+}
+
+// handlePrometheus exposes host and container metrics in Prometheus text format.
+// Like /health, this endpoint is mounted outside /api and is unauthenticated;
+// --prometheus=false turns it off.
+func (s *Server) handlePrometheus(w http.ResponseWriter, r *http.Request) {
+	m, err := s.cachedMetrics()
+	if err != nil {
+		slog.Error("Failed to get metrics for Prometheus", "error", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p := &promWriter{w: w}
+
+	if m.CPU != nil {
+		p.gauge("servertui_cpu_usage_percent", "CPU usage across all cores.", m.CPU.UsagePercent)
+		p.gauge("servertui_cpu_cores", "Number of logical CPU cores.", float64(m.CPU.Cores))
+	}
+
+	if m.Memory != nil {
+		p.gauge("servertui_memory_total_bytes", "Total physical memory.", float64(m.Memory.Total))
+		p.gauge("servertui_memory_used_bytes", "Used physical memory.", float64(m.Memory.Used))
+		p.gauge("servertui_memory_free_bytes", "Free physical memory.", float64(m.Memory.Free))
+		p.gauge("servertui_memory_usage_percent", "Physical memory usage.", m.Memory.UsagePercent)
+	}
+
+	if m.Disk != nil {
+		mount := m.Disk.MountPoint
+		p.gauge("servertui_disk_total_bytes", "Total disk space.", float64(m.Disk.Total), "mountpoint", mount)
+		p.gauge("servertui_disk_used_bytes", "Used disk space.", float64(m.Disk.Used), "mountpoint", mount)
+		p.gauge("servertui_disk_free_bytes", "Free disk space.", float64(m.Disk.Free), "mountpoint", mount)
+		p.gauge("servertui_disk_usage_percent", "Disk space usage.", m.Disk.UsagePercent, "mountpoint", mount)
+	}
+
+	if m.Network != nil {
+		p.counter("servertui_network_receive_bytes_total", "Bytes received on all interfaces.", float64(m.Network.BytesRecv))
+		p.counter("servertui_network_transmit_bytes_total", "Bytes sent on all interfaces.", float64(m.Network.BytesSent))
+		p.counter("servertui_network_receive_packets_total", "Packets received on all interfaces.", float64(m.Network.PacketsRecv))
+		p.counter("servertui_network_transmit_packets_total", "Packets sent on all interfaces.", float64(m.Network.PacketsSent))
+	}
+
+	dm := s.dockerManager.Load()
+	if dm == nil {
+		p.gauge("servertui_docker_up", "Whether the Docker daemon is reachable.", 0)
+		return
+	}
+
+	containers, err := dm.ListContainers(r.Context(), docker.ContainerFilter{})
+	if err != nil {
+		slog.Warn("Failed to list containers for Prometheus", "error", err)
+		p.gauge("servertui_docker_up", "Whether the Docker daemon is reachable.", 0)
+		return
+	}
+	p.gauge("servertui_docker_up", "Whether the Docker daemon is reachable.", 1)
+
+	p.header("servertui_container_running", "Whether the container is running.", "gauge")
+	for _, c := range containers {
+		running := 0.0
+		if c.State == "running" {
+			running = 1
+		}
+		p.sample("servertui_container_running", running, "id", c.ID, "name", c.Name, "image", c.Image, "state", c.State)
+	}
+
+	stats := runningContainerStats(r.Context(), dm, containers)
+	p.header("servertui_container_cpu_usage_percent", "Container CPU usage since the previous sample, where 100 is one core.", "gauge")
+	for _, st := range stats {
+		p.sample("servertui_container_cpu_usage_percent", st.CPUPercent, "id", st.ID, "name", st.Name)
+	}
+	p.header("servertui_container_memory_usage_bytes", "Container memory usage excluding reclaimable page cache, as docker stats reports it.", "gauge")
+	for _, st := range stats {
+		p.sample("servertui_container_memory_usage_bytes", float64(st.MemoryUsage), "id", st.ID, "name", st.Name)
+	}
+	p.header("servertui_container_memory_limit_bytes", "Container memory limit.", "gauge")
+	for _, st := range stats {
+		p.sample("servertui_container_memory_limit_bytes", float64(st.MemoryLimit), "id", st.ID, "name", st.Name)
+	}
+}
+
+// runningContainerStats samples the running containers, a few at a time, in
+// the order given. Containers whose stats can't be read are left out.
+func runningContainerStats(ctx context.Context, dm *docker.Manager, containers []docker.Container) []*docker.ContainerStats {
+	var running []docker.Container
+	for _, c := range containers {
+		if c.State == "running" {
+			running = append(running, c)
+		}
+	}
+
+	stats := make([]*docker.ContainerStats, len(running))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(promStatsWorkers, len(running)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				c := running[i]
+				st, err := dm.GetContainerStats(ctx, c.ID)
+				if err != nil {
+					slog.Debug("Failed to sample container stats for Prometheus", "container", c.ID, "error", err)
+					continue
+				}
+				// Label like servertui_container_running so the series join
+				st.ID, st.Name = c.ID, c.Name
+				stats[i] = st
+			}
+		}()
+	}
+	for i := range running {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	result := stats[:0]
+	for _, st := range stats {
+		if st != nil {
+			result = append(result, st)
+		}
+	}
+	return result
+}
@@ -4,99 +4,575 @@ package server
 import (
 	"bufio"
 	"context"
+	"crypto/tls"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand/v2"
 	"net"
 	"net/http"
+	"os"
+	"runtime/debug"
+	"sync/atomic"
 	"time"
 
+	"github.com/aniket/servertui/agent/internal/alerts"
 	"github.com/aniket/servertui/agent/internal/config"
+	"github.com/aniket/servertui/agent/internal/diagnostics"
 	"github.com/aniket/servertui/agent/internal/docker"
+	"github.com/aniket/servertui/agent/internal/execpolicy"
+	"github.com/aniket/servertui/agent/internal/logging"
 	"github.com/aniket/servertui/agent/internal/metrics"
+	"github.com/aniket/servertui/agent/internal/oom"
+	"github.com/aniket/servertui/agent/internal/services"
 	"github.com/aniket/servertui/agent/internal/updates"
 	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
 )
 
 // Server is the main HTTP/WebSocket server.
 type Server struct {
 	config           *config.Config
 	router           *mux.Router
+	upgrader         websocket.Upgrader
 	httpServer       *http.Server
 	metricsCollector *metrics.Collector
-	dockerManager    *docker.Manager
+	history          *metrics.History
+	oomEvents        oom.Recorder
+	alerts           *alerts.Engine
 	updatesManager   *updates.Manager
+	servicesManager  *services.Manager
+	connectivity     *diagnostics.Checker
+	tlsConfig        *tls.Config
+	certs            *certReloader
+	pprofServer      *http.Server
+
+	// dockerManager is nil until the Docker daemon is reachable; see reconnectDocker
+	dockerManager atomic.Pointer[docker.Manager]
+
+	// Hot-reloadable settings, swapped by Reload
+	execPolicy      atomic.Pointer[execpolicy.Policy]
+	metricsInterval atomic.Int64
+
+	// wsConns tracks hijacked WebSocket connections for draining on shutdown
+	wsConns wsRegistry
+
+	// startTime is when the server was created, for the agent's uptime
+	startTime time.Time
+
+	// effective is the configuration in effect: the startup settings with
+	// hot-reloaded ones applied, and the changes still waiting on a restart
+	effective atomic.Pointer[effectiveConfig]
+
+	// instance names this agent in the instanceHeader of every response
+	instance string
+
+	// shutdownCtx is canceled when Shutdown begins, stopping streaming loops
+	shutdownCtx    context.Context
+	cancelShutdown context.CancelFunc
 }
 
 // New creates a new server with the given configuration.
-func New(cfg *config.Config) *Server {
+func New(cfg *config.Config) (*Server, error) {
+	policy, err := execpolicy.New(cfg.ExecDisabled, cfg.ExecAllowlistPath, cfg.ExecDirsList())
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]alerts.Rule, 0, len(cfg.AlertRules))
+	for _, expr := range cfg.AlertRules {
+		rule, err := alerts.ParseRule(expr)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	// TLS is served whenever a certificate and key are configured
+	var certs *certReloader
+	var tlsConfig *tls.Config
+	if cfg.TLSCertPath != "" && cfg.TLSKeyPath != "" {
+		if certs, err = newCertReloader(cfg.TLSCertPath, cfg.TLSKeyPath); err != nil {
+			return nil, err
+		}
+		if tlsConfig, err = newTLSConfig(cfg, certs); err != nil {
+			return nil, err
+		}
+	}
+
 	s := &Server{
-		config:           cfg,
-		router:           mux.NewRouter(),
-		metricsCollector: metrics.NewCollector(),
-		updatesManager:   updates.NewManager(),
+		config:   cfg,
+		router:   mux.NewRouter(),
+		upgrader: newUpgrader(cfg),
+		metricsCollector: metrics.NewCollector(metrics.Options{
+			GPU:      cfg.GPUMetrics,
+			Disabled: cfg.DisabledMetricsList(),
+			Mounts: metrics.MountFilter{
+				Include:        cfg.DiskIncludeList(),
+				Exclude:        cfg.DiskExcludeList(),
+				ExcludeFSTypes: cfg.DiskExcludeFSTypesList(),
+			},
+		}),
+		updatesManager:  updates.NewManager(cfg.CommandTimeout, cfg.UpdatesCacheTTL),
+		servicesManager: services.NewManager(cfg.CommandTimeout),
+		connectivity: diagnostics.NewChecker(diagnostics.Targets{
+			DNSHost:   cfg.DiagDNSHost,
+			TCPTarget: cfg.DiagTCPTarget,
+			HTTPURL:   cfg.DiagHTTPURL,
+		}, cfg.DiagTimeout),
+		tlsConfig: tlsConfig,
+		certs:     certs,
+		alerts:    alerts.NewEngine(rules),
 	}
+	if cfg.HistoryRetention > 0 {
+		s.history = metrics.NewHistory(int(cfg.HistoryRetention / cfg.HistoryInterval))
+	}
+	s.execPolicy.Store(policy)
+	s.metricsInterval.Store(int64(cfg.MetricsInterval))
+	s.shutdownCtx, s.cancelShutdown = context.WithCancel(context.Background())
+	s.startTime = time.Now()
+	s.instance = cfg.Instance()
+	s.effective.Store(&effectiveConfig{cfg: cfg})
 
 	// Try to initialize Docker manager (may fail if Docker not available)
-	dockerMgr, err := docker.NewManager()
+	dockerMgr, err := docker.NewManager(dockerOptions(s.config))
 	if err != nil {
-		log.Printf("Docker not available: %v", err)
+		slog.Warn("Docker not available, will retry in the background", "error", err)
 	} else {
-		s.dockerManager = dockerMgr
+		slog.Info("Connected to Docker", "apiVersion", dockerMgr.APIVersion())
+		s.dockerManager.Store(dockerMgr)
 	}
 
 	s.setupRoutes()
-	return s
+	return s, nil
 }
 
 // setupRoutes configures all HTTP routes.
 func (s *Server) setupRoutes() {
+	// Tag each request with an ID for log correlation
+	s.router.Use(requestIDMiddleware)
+	s.router.Use(instanceMiddleware(s.instance))
+	// Turn handler panics into 500s instead of dropping the connection
+	s.router.Use(recoverMiddleware)
+	// Reject oversized request bodies
+	s.router.Use(bodyLimitMiddleware(s.config.MaxBodyBytes))
 	// Logging middleware for all routes
 	s.router.Use(loggingMiddleware)
 	// CORS middleware for all routes
 	s.router.Use(corsMiddleware)
+	// Compress large responses for clients that accept gzip
+	s.router.Use(gzipMiddleware)
 
-	// Health check
+	// Health checks: liveness is cheap, readiness probes subsystems
 	s.router.HandleFunc("/health", s.handleHealth).Methods("GET")
+	s.router.HandleFunc("/readyz", s.handleReady).Methods("GET")
+
+	// Prometheus scrape endpoint (unauthenticated, like health)
+	if s.config.Prometheus {
+		s.router.HandleFunc("/metrics", s.handlePrometheus).Methods("GET")
+	}
 
 	// API routes
 	api := s.router.PathPrefix("/api").Subrouter()
 	api.HandleFunc("/system", s.handleSystemInfo).Methods("GET")
+	api.HandleFunc("/agent/version", s.handleAgentVersion).Methods("GET")
+	api.HandleFunc("/agent/runtime", s.handleAgentRuntime).Methods("GET")
+	api.HandleFunc("/agent/config", s.handleAgentConfig).Methods("GET")
 	api.HandleFunc("/metrics", s.handleMetrics).Methods("GET")
+	api.HandleFunc("/dashboard", s.handleDashboard).Methods("GET")
+	api.HandleFunc("/metrics/history", s.handleMetricsHistory).Methods("GET")
+	api.HandleFunc("/metrics/cpu", handleMetric("cpu", s.metricsCollector.GetCPUMetrics)).Methods("GET")
+	api.HandleFunc("/metrics/memory", handleMetric("memory", s.metricsCollector.GetMemoryMetrics)).Methods("GET")
+	api.HandleFunc("/metrics/disk", handleMetric("disk", s.metricsCollector.GetDiskMetrics)).Methods("GET")
+	api.HandleFunc("/metrics/network", s.handleNetworkMetrics).Methods("GET")
+	api.HandleFunc("/processes", s.handleProcessesByName).Methods("GET")
+	api.HandleFunc("/processes/{pid:[0-9]+}", s.handleProcess).Methods("GET")
+	api.HandleFunc("/processes/{pid:[0-9]+}/signal", s.handleSignalProcess).Methods("POST")
+	api.HandleFunc("/users", s.handleUsers).Methods("GET")
+	api.HandleFunc("/network/connections", s.handleConnections).Methods("GET")
+	api.HandleFunc("/network/summary", s.handleTCPSummary).Methods("GET")
+	api.HandleFunc("/diagnostics/connectivity", s.handleConnectivity).Methods("GET")
+	api.HandleFunc("/diagnostics/disk", s.handleDiskProbe).Methods("GET")
 	api.HandleFunc("/docker", s.handleDocker).Methods("GET")
+	api.HandleFunc("/docker/info", s.handleDockerInfo).Methods("GET")
+	api.HandleFunc("/docker/containers", s.handleContainers).Methods("GET")
+	api.HandleFunc("/docker/containers/batch", s.handleContainerBatch).Methods("POST")
+	api.HandleFunc("/docker/images", s.handleImages).Methods("GET")
+	api.HandleFunc("/docker/images/tag", s.handleImageTag).Methods("POST")
+	// Image names may contain slashes, e.g. ghcr.io/org/app:v1
+	api.HandleFunc("/docker/images/{image:.+}/inspect", s.handleImageInspect).Methods("GET")
+	api.HandleFunc("/docker/compose/projects", s.handleComposeProjects).Methods("GET")
+	api.HandleFunc("/docker/compose/projects/{name}/{action:start|stop|restart}", s.handleComposeAction).Methods("POST")
 	api.HandleFunc("/docker/containers/{id}/start", s.handleContainerStart).Methods("POST")
 	api.HandleFunc("/docker/containers/{id}/stop", s.handleContainerStop).Methods("POST")
+	api.HandleFunc("/docker/containers/{id}/kill", s.handleContainerKill).Methods("POST")
+	api.HandleFunc("/docker/containers/{id}/logs", s.handleContainerLogs).Methods("GET")
+	api.HandleFunc("/docker/containers/{id}/inspect", s.handleContainerInspect).Methods("GET")
+	api.HandleFunc("/docker/containers/{id}/stats", s.handleContainerStats).Methods("GET")
 	api.HandleFunc("/updates", s.handleUpdates).Methods("GET")
+	api.HandleFunc("/updates/held", s.handleHeldPackages).Methods("GET")
+	api.HandleFunc("/updates/status", s.handleUpdatesStatus).Methods("GET")
 	api.HandleFunc("/updates/apply", s.handleApplyUpdate).Methods("POST")
 	api.HandleFunc("/updates/apply-all", s.handleApplyAllUpdates).Methods("POST")
+	api.HandleFunc("/packages/search", s.handleSearchPackages).Methods("GET")
+	api.HandleFunc("/packages/install", s.handleInstallPackage).Methods("POST")
+	api.HandleFunc("/packages/remove", s.handleRemovePackage).Methods("POST")
 	api.HandleFunc("/exec", s.handleExec).Methods("POST")
+	api.HandleFunc("/host/{action:reboot|shutdown}", s.handleHostAction).Methods("POST")
+	api.HandleFunc("/host/cancel", s.handleHostCancel).Methods("POST")
+	api.HandleFunc("/services", s.handleServices).Methods("GET")
+	api.HandleFunc("/services/{name}/{action:start|stop|restart}", s.handleServiceAction).Methods("POST")
+	api.HandleFunc("/logs/journal", s.handleJournal).Methods("GET")
+	api.HandleFunc("/events/oom", s.handleOOMEvents).Methods("GET")
+	api.HandleFunc("/alerts", s.handleAlerts).Methods("GET")
 
 	// WebSocket route
 	s.router.HandleFunc("/ws/metrics", s.handleMetricsWS)
 	s.router.HandleFunc("/ws/docker/logs", s.handleDockerLogsWS)
+	s.router.HandleFunc("/ws/docker/events", s.handleDockerEventsWS)
+	s.router.HandleFunc("/ws/docker/build", s.handleDockerBuildWS)
+	s.router.HandleFunc("/ws/alerts", s.handleAlertsWS)
+	s.router.HandleFunc("/ws/logs/journal", s.handleJournalWS)
+	s.router.HandleFunc("/ws/exec", s.handleExecWS)
 }
 
-// Start starts the HTTP server.
-func (s *Server) Start() error {
-	addr := fmt.Sprintf(":%d", s.config.Port)
+// socketMode is the permission set on the Unix domain socket.
+const socketMode = 0660
 
+// Start starts the HTTP server on TCP, or on a Unix socket if one is configured.
+// When a client CA is configured the listener is wrapped in TLS requiring client certificates.
+func (s *Server) Start() error {
 	s.httpServer = &http.Server{
-		Addr:         addr,
 		Handler:      s.router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		ReadTimeout:  s.config.HTTPReadTimeout,
+		WriteTimeout: s.config.HTTPWriteTimeout,
+		IdleTimeout:  s.config.HTTPIdleTimeout,
 	}
 
-	log.Printf("Starting agent server on %s (HTTP)", addr)
-	return s.httpServer.ListenAndServe()
+	listener, err := s.listen()
+	if err != nil {
+		return err
+	}
+
+	if s.dockerManager.Load() == nil {
+		go s.reconnectDocker(s.shutdownCtx, dockerRetryInterval)
+	}
+	go s.watchOOM(s.shutdownCtx)
+	if len(s.alerts.Rules()) > 0 {
+		go s.logAlerts(s.shutdownCtx)
+		if s.config.AlertWebhook != "" {
+			notifications, _ := s.alerts.Subscribe(alertBufferSize)
+			go alerts.NewWebhook(s.config.AlertWebhook, s.instance).Run(s.shutdownCtx, notifications)
+		}
+		go s.evaluateAlerts(s.shutdownCtx, s.config.AlertInterval)
+	}
+	if s.history != nil {
+		go s.recordHistory(s.shutdownCtx, s.config.HistoryInterval)
+	}
+	if s.config.EnablePprof {
+		s.pprofServer = newPprofServer(s.config.PprofAddr)
+		go servePprof(s.pprofServer)
+	}
+	if s.config.UpdatesRefreshInterval > 0 {
+		go s.updatesManager.RunRefresher(s.shutdownCtx, s.config.UpdatesRefreshInterval)
+	}
+
+	if s.tlsConfig != nil {
+		listener = tls.NewListener(listener, s.tlsConfig)
+		go s.certs.watch(s.shutdownCtx, certWatchInterval)
+		mode := "HTTPS"
+		if s.config.ClientCAPath != "" {
+			mode = "HTTPS, mTLS"
+		}
+		slog.Info("Starting agent server ("+mode+")", "addr", listener.Addr().String())
+	} else {
+		slog.Info("Starting agent server (HTTP)", "addr", listener.Addr().String())
+	}
+	return s.httpServer.Serve(listener)
+}
+
+// listen opens the configured TCP address or Unix socket.
+func (s *Server) listen() (net.Listener, error) {
+	if s.config.SocketPath == "" {
+		return net.Listen("tcp", s.config.ListenAddr())
+	}
+
+	// Remove a stale socket left behind by an unclean exit, but never other files
+	if fi, err := os.Lstat(s.config.SocketPath); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", s.config.SocketPath)
+		}
+		if err := os.Remove(s.config.SocketPath); err != nil {
+			return nil, err
+		}
+	}
+
+	listener, err := net.Listen("unix", s.config.SocketPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(s.config.SocketPath, socketMode); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// Reload applies the hot-reloadable settings from cfg: log level, metrics
+// interval and exec policy. Other changed settings are reported as needing
+// a restart and left as they were at startup.
+func (s *Server) Reload(cfg *config.Config) error {
+	policy, err := execpolicy.New(cfg.ExecDisabled, cfg.ExecAllowlistPath, cfg.ExecDirsList())
+	if err != nil {
+		return err
+	}
+	level, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return err
+	}
+
+	logging.SetLevel(level)
+	s.metricsInterval.Store(int64(cfg.MetricsInterval))
+	s.execPolicy.Store(policy)
+
+	effective := *s.config
+	effective.LogLevel = cfg.LogLevel
+	effective.MetricsInterval = cfg.MetricsInterval
+	effective.ExecDisabled = cfg.ExecDisabled
+	effective.ExecAllowlistPath = cfg.ExecAllowlistPath
+	effective.ExecDirs = cfg.ExecDirs
+	pending := s.config.RestartRequired(cfg)
+	s.effective.Store(&effectiveConfig{cfg: &effective, pendingRestart: pending})
+
+	for _, name := range pending {
+		slog.Warn("Setting changed but requires a restart to take effect", "setting", name)
+	}
+	slog.Info("Configuration reloaded",
+		"logLevel", cfg.LogLevel,
+		"metricsInterval", cfg.MetricsInterval,
+		"execDisabled", cfg.ExecDisabled,
+		"execAllowlist", cfg.ExecAllowlistPath)
+	return nil
+}
+
+// effectiveConfig is the configuration in effect after the last reload.
+type effectiveConfig struct {
+	cfg *config.Config
+	// pendingRestart names changed settings that only apply after a restart
+	pendingRestart []string
+}
+
+// ReloadCertificates re-reads the TLS certificate and key from disk.
+// It is a no-op when the server is not serving TLS.
+func (s *Server) ReloadCertificates() error {
+	if s.certs == nil {
+		return nil
+	}
+	err := s.certs.reload()
+	s.certs.logReload(err)
+	return err
+}
+
+// dockerOptions returns the Docker connection settings from cfg.
+func dockerOptions(cfg *config.Config) docker.Options {
+	return docker.Options{
+		Host:        cfg.DockerHost,
+		TLSCAPath:   cfg.DockerTLSCAPath,
+		TLSCertPath: cfg.DockerTLSCertPath,
+		TLSKeyPath:  cfg.DockerTLSKeyPath,
+		Timeout:     cfg.DockerTimeout,
+	}
+}
+
+// dockerRetryInterval is how often to retry connecting to an unavailable Docker daemon.
+const dockerRetryInterval = 30 * time.Second
+
+// reconnectDocker retries connecting to Docker every interval until it
+// succeeds or ctx is canceled, so the agent recovers when the daemon starts late.
+func (s *Server) reconnectDocker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		dockerMgr, err := docker.NewManager(dockerOptions(s.config))
+		if err != nil {
+			slog.Debug("Docker still not available", "error", err)
+			continue
+		}
+		if ctx.Err() != nil {
+			dockerMgr.Close()
+			return
+		}
+		s.dockerManager.Store(dockerMgr)
+		slog.Info("Connected to Docker", "apiVersion", dockerMgr.APIVersion())
+		return
+	}
+}
+
+// oomWatchRetry is how long to wait before resubscribing to Docker OOM events.
+const oomWatchRetry = 30 * time.Second
+
+// watchOOM records Docker OOM events until ctx is canceled, resubscribing
+// if Docker is unavailable or the event stream drops.
+func (s *Server) watchOOM(ctx context.Context) {
+	filter := docker.EventFilter{Actions: []string{"oom"}}
+	for {
+		if dm := s.dockerManager.Load(); dm != nil {
+			events := make(chan docker.Event)
+			streamErr := make(chan error, 1)
+			go func() {
+				streamErr <- dm.StreamEvents(ctx, filter, events)
+			}()
+
+			for e := range events {
+				slog.Warn("Container killed by OOM", "container", e.Name, "id", e.ContainerID, "image", e.Image)
+				s.oomEvents.Add(oom.Event{
+					Source:        oom.SourceDocker,
+					Time:          e.Time,
+					ContainerID:   e.ContainerID,
+					ContainerName: e.Name,
+					Image:         e.Image,
+				})
+			}
+			if err := <-streamErr; ctx.Err() == nil {
+				slog.Warn("OOM event watch interrupted, retrying", "error", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(oomWatchRetry):
+		}
+	}
+}
+
+// evaluateAlerts checks the alert rules against fresh metrics every interval
+// until ctx is canceled.
+func (s *Server) evaluateAlerts(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(s.jittered(interval))
+	defer ticker.Stop()
+
+	for {
+		if m, err := s.metricsCollector.GetMetrics(); err != nil {
+			slog.Warn("Failed to sample metrics for alerts", "error", err)
+		} else {
+			s.alerts.Evaluate(m, time.Now())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.rejitter(ticker, interval)
+		}
+	}
+}
+
+// logAlerts logs alerts as they fire and resolve until ctx is canceled.
+func (s *Server) logAlerts(ctx context.Context) {
+	notifications, unsubscribe := s.alerts.Subscribe(alertBufferSize)
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-notifications:
+			if n.State == alerts.StateFiring {
+				slog.Warn("Alert firing", "rule", n.Rule, "mount", n.Mount, "value", n.Value)
+			} else {
+				slog.Info("Alert resolved", "rule", n.Rule, "mount", n.Mount, "value", n.Value)
+			}
+		}
+	}
+}
+
+// recordHistory samples metrics into s.history every interval until ctx is canceled.
+func (s *Server) recordHistory(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(s.jittered(interval))
+	defer ticker.Stop()
+
+	for {
+		if m, err := s.metricsCollector.GetMetrics(); err != nil {
+			slog.Warn("Failed to sample metrics for history", "error", err)
+		} else {
+			s.history.Add(m)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.rejitter(ticker, interval)
+		}
+	}
+}
+
+// jittered returns interval moved randomly by up to the configured metrics
+// jitter percentage either way, so a fleet of agents doesn't sample and push
+// in lockstep.
+func (s *Server) jittered(interval time.Duration) time.Duration {
+	spread := int64(interval) * int64(s.config.MetricsJitter) / 100
+	if spread <= 0 {
+		return interval
+	}
+	return interval + time.Duration(rand.Int64N(2*spread+1)-spread)
+}
+
+// rejitter gives ticker a freshly jittered period for its next tick. Without
+// jitter it leaves the ticker alone, so its phase doesn't drift.
+func (s *Server) rejitter(ticker *time.Ticker, interval time.Duration) {
+	if s.config.MetricsJitter > 0 {
+		ticker.Reset(s.jittered(interval))
+	}
 }
 
 // Shutdown gracefully shuts down the server.
+// Active WebSocket clients are sent a close frame and given until ctx
+// expires to disconnect before their connections are closed forcibly.
 func (s *Server) Shutdown(ctx context.Context) error {
-	if s.dockerManager != nil {
-		s.dockerManager.Close()
+	// httpServer is unset if Start failed before listening
+	var err error
+	if s.httpServer != nil {
+		err = s.httpServer.Shutdown(ctx)
+	}
+	if s.pprofServer != nil {
+		s.pprofServer.Close()
+	}
+
+	s.wsConns.closeAll()
+	s.cancelShutdown()
+	if wsErr := s.wsConns.wait(ctx); wsErr != nil {
+		slog.Warn("Timed out draining WebSocket connections", "error", wsErr)
+	}
+
+	if dm := s.dockerManager.Load(); dm != nil {
+		dm.Close()
+	}
+	if s.config.SocketPath != "" {
+		os.Remove(s.config.SocketPath)
+	}
+	return err
+}
+
+// instanceHeader carries the agent's instance name on every response, so a
+// controller talking to many agents can attribute what it receives.
+const instanceHeader = "X-Agent-Instance"
+
+// instanceMiddleware sets instanceHeader to name.
+func instanceMiddleware(name string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(instanceHeader, name)
+			next.ServeHTTP(w, r)
+		})
 	}
-	return s.httpServer.Shutdown(ctx)
 }
 
 // corsMiddleware adds CORS headers to responses.
@@ -104,7 +580,8 @@ func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, If-None-Match, "+requestIDHeader)
+		w.Header().Set("Access-Control-Expose-Headers", requestIDHeader+", ETag, X-Checked-At, X-Sampled-At, "+instanceHeader)
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -115,18 +592,68 @@ func corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// recoverMiddleware recovers from handler panics, logging the stack trace and
+// returning 500 so one bad request can't take down the agent.
+func recoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			// Deliberate aborts are handled by net/http
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			slog.Error("Panic serving request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"remote", r.RemoteAddr,
+				"requestId", requestID(r),
+				"panic", err,
+				"stack", string(debug.Stack()))
+			writeError(w, http.StatusInternalServerError, "internal server error")
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bodyLimitMiddleware caps request bodies at limit bytes. Handlers see an
+// *http.MaxBytesError when reading past it. A limit of 0 disables the cap.
+func bodyLimitMiddleware(limit int64) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if limit == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // loggingMiddleware logs all incoming requests.
 func loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		log.Printf("[REQUEST] %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+		slog.Debug("Request received", "method", r.Method, "path", r.URL.Path, "remote", r.RemoteAddr, "requestId", requestID(r))
 
 		// Wrap response writer to capture status code
 		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 		next.ServeHTTP(wrapped, r)
 
-		duration := time.Since(start)
-		log.Printf("[RESPONSE] %s %s -> %d (%v)", r.Method, r.URL.Path, wrapped.statusCode, duration)
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"remote", r.RemoteAddr,
+			"status", wrapped.statusCode,
+			"duration", time.Since(start),
+			"requestId", requestID(r),
+		}
+		if subject := clientSubject(r); subject != "" {
+			attrs = append(attrs, "client", subject)
+		}
+		slog.Info("Request handled", attrs...)
 	})
 }
 
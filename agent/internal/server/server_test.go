@@ -0,0 +1,222 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aniket/servertui/agent/internal/config"
+	"github.com/aniket/servertui/agent/internal/docker"
+)
+
+// newTestServer creates a Server from the default configuration, adjusted
+// by configure, and serves its routes over plain HTTP. Docker points at a
+// socket that doesn't exist so the server starts without it.
+func newTestServer(t *testing.T, configure func(*config.Config)) (*Server, *httptest.Server) {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.DockerHost = "unix://" + filepath.Join(t.TempDir(), "docker.sock")
+	if configure != nil {
+		configure(cfg)
+	}
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(s.router)
+	t.Cleanup(func() {
+		s.cancelShutdown()
+		srv.Close()
+	})
+	return s, srv
+}
+
+// decodeError reads an ErrorResponse body written by writeError.
+func decodeError(t *testing.T, resp *http.Response) ErrorResponse {
+	t.Helper()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var body ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("error body isn't an ErrorResponse: %v", err)
+	}
+	return body
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	// Same order as setupRoutes; the client accepts gzip by default
+	srv := httptest.NewServer(recoverMiddleware(gzipMiddleware(mux)))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/panic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", resp.StatusCode)
+	}
+	if body := decodeError(t, resp); body.Error != "internal server error" {
+		t.Errorf("error = %q, want internal server error", body.Error)
+	}
+
+	// The server keeps serving after the panic
+	ok, err := http.Get(srv.URL + "/ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok.Body.Close()
+	if ok.StatusCode != http.StatusOK {
+		t.Errorf("status after panic = %d, want 200", ok.StatusCode)
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	_, srv := newTestServer(t, func(cfg *config.Config) { cfg.MaxBodyBytes = 64 })
+
+	post := func(body string) *http.Response {
+		t.Helper()
+		resp, err := http.Post(srv.URL+"/api/exec", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	resp := post(`{"command":"` + strings.Repeat("x", 100) + `"}`)
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413", resp.StatusCode)
+	}
+	if body := decodeError(t, resp); body.Error != "request body exceeds 64 bytes" {
+		t.Errorf("error = %q, want request body exceeds 64 bytes", body.Error)
+	}
+
+	// A body within the limit reaches the handler
+	resp = post(`{"command":""}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", resp.StatusCode)
+	}
+	if body := decodeError(t, resp); body.Error != "command required" {
+		t.Errorf("error = %q, want command required", body.Error)
+	}
+}
+
+func TestExecEnvAllowlist(t *testing.T) {
+	allowlist := filepath.Join(t.TempDir(), "allowlist")
+	if err := os.WriteFile(allowlist, []byte("echo ok\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, srv := newTestServer(t, func(cfg *config.Config) { cfg.ExecAllowlistPath = allowlist })
+
+	post := func(env string) *http.Response {
+		t.Helper()
+		body := `{"command":"echo ok","env":` + env + `}`
+		resp, err := http.Post(srv.URL+"/api/exec", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	for _, env := range []string{`{"PATH":"/tmp"}`, `{"LD_PRELOAD":"/tmp/x.so"}`, `{"BASH_ENV":"/tmp/x"}`} {
+		resp := post(env)
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("env %s: status = %d, want 403", env, resp.StatusCode)
+		}
+		if body := decodeError(t, resp); !strings.Contains(body.Error, "not permitted") {
+			t.Errorf("env %s: error = %q, want not permitted", env, body.Error)
+		}
+	}
+
+	// Other variables are still passed through
+	if resp := post(`{"GREETING":"hi"}`); resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+}
+
+// fakeDockerDaemon answers pings and lists n containers and n images.
+func fakeDockerDaemon(t *testing.T, n int) string {
+	t.Helper()
+	var containers, images []map[string]any
+	for i := range n {
+		id := fmt.Sprintf("%064x", i)
+		containers = append(containers, map[string]any{"Id": id, "Names": []string{fmt.Sprintf("/c%d", i)}, "State": "running"})
+		images = append(images, map[string]any{"Id": "sha256:" + id, "RepoTags": []string{fmt.Sprintf("img%d:latest", i)}})
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Api-Version", "1.43")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/_ping"):
+			io.WriteString(w, "OK")
+		case strings.HasSuffix(r.URL.Path, "/containers/json"):
+			json.NewEncoder(w).Encode(containers)
+		case strings.HasSuffix(r.URL.Path, "/images/json"):
+			json.NewEncoder(w).Encode(images)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return "tcp://" + srv.Listener.Addr().String()
+}
+
+func TestDockerStatusCapped(t *testing.T) {
+	const n = defaultPageLimit + 20
+	host := fakeDockerDaemon(t, n)
+	_, srv := newTestServer(t, func(cfg *config.Config) { cfg.DockerHost = host })
+
+	get := func(path string, v any) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: status = %d, want 200", path, resp.StatusCode)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(name string, status *docker.Status, wantLen int) {
+		t.Helper()
+		if len(status.Containers) != wantLen || len(status.Images) != wantLen {
+			t.Errorf("%s: got %d containers and %d images, want %d of each", name, len(status.Containers), len(status.Images), wantLen)
+		}
+		if status.TotalContainers != n || status.TotalImages != n {
+			t.Errorf("%s: totals = %d containers, %d images, want %d", name, status.TotalContainers, status.TotalImages, n)
+		}
+	}
+
+	var status docker.Status
+	get("/api/docker", &status)
+	check("/api/docker", &status, defaultPageLimit)
+
+	status = docker.Status{}
+	get("/api/docker?offset=100", &status)
+	check("/api/docker?offset=100", &status, n-100)
+
+	var dashboard DashboardResponse
+	get("/api/dashboard", &dashboard)
+	if dashboard.Docker == nil {
+		t.Fatalf("dashboard has no Docker section: %v", dashboard.Errors)
+	}
+	check("/api/dashboard", dashboard.Docker, defaultPageLimit)
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/aniket/servertui/agent/internal/config"
+)
+
+// certWatchInterval is how often the certificate files are checked for changes.
+const certWatchInterval = 30 * time.Second
+
+// certReloader serves the current certificate and swaps in a new one when
+// the files on disk change, so rotation doesn't require a restart.
+type certReloader struct {
+	certPath string
+	keyPath  string
+	cert     atomic.Pointer[tls.Certificate]
+
+	// certModified and keyModified are the last seen file mtimes (watch goroutine only)
+	certModified time.Time
+	keyModified  time.Time
+}
+
+// newCertReloader loads the initial key pair.
+func newCertReloader(certPath, keyPath string) (*certReloader, error) {
+	c := &certReloader{certPath: certPath, keyPath: keyPath}
+	c.certModified, c.keyModified = c.modTimes()
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// reload loads the key pair from disk. On failure the current certificate is kept.
+func (c *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(c.certPath, c.keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+	c.cert.Store(&cert)
+	return nil
+}
+
+// getCertificate implements tls.Config.GetCertificate.
+func (c *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return c.cert.Load(), nil
+}
+
+// modTimes returns the modification times of the certificate and key files.
+func (c *certReloader) modTimes() (cert, key time.Time) {
+	if fi, err := os.Stat(c.certPath); err == nil {
+		cert = fi.ModTime()
+	}
+	if fi, err := os.Stat(c.keyPath); err == nil {
+		key = fi.ModTime()
+	}
+	return cert, key
+}
+
+// watch polls the certificate files and reloads them when either changes.
+func (c *certReloader) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			certModified, keyModified := c.modTimes()
+			if certModified.Equal(c.certModified) && keyModified.Equal(c.keyModified) {
+				continue
+			}
+			c.certModified, c.keyModified = certModified, keyModified
+			c.logReload(c.reload())
+		}
+	}
+}
+
+// logReload records the outcome of a certificate reload.
+func (c *certReloader) logReload(err error) {
+	if err != nil {
+		slog.Error("Failed to reload TLS certificate, keeping current one", "cert", c.certPath, "error", err)
+		return
+	}
+	attrs := []any{"cert", c.certPath}
+	if leaf := c.cert.Load().Leaf; leaf != nil {
+		attrs = append(attrs, "subject", leaf.Subject.String(), "notAfter", leaf.NotAfter)
+	}
+	slog.Info("Reloaded TLS certificate", attrs...)
+}
+
+// newTLSConfig builds the server TLS configuration around certs. Client
+// certificates are required and verified only when cfg.ClientCAPath is set.
+func newTLSConfig(cfg *config.Config, certs *certReloader) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		GetCertificate: certs.getCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}
+	if cfg.ClientCAPath == "" {
+		return tlsConfig, nil
+	}
+
+	caPEM, err := os.ReadFile(cfg.ClientCAPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates found in client CA bundle %s", cfg.ClientCAPath)
+	}
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	tlsConfig.ClientCAs = pool
+	return tlsConfig, nil
+}
+
+// clientSubject returns the subject of the verified client certificate,
+// or an empty string for requests that did not use mTLS.
+func clientSubject(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return ""
+	}
+	return r.TLS.PeerCertificates[0].Subject.String()
+}
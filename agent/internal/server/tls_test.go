@@ -0,0 +1,302 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aniket/servertui/agent/internal/config"
+)
+
+// testCert is a generated certificate with its key, as parsed and PEM forms.
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCert generates a certificate for name, signed by parent or
+// self-signed when parent is nil.
+func newTestCert(t *testing.T, name string, parent *testCert, usage x509.ExtKeyUsage) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     []string{name},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func (c *testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+	pair, err := tls.X509KeyPair(c.certPEM, c.keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pair
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "test-ca", nil, x509.ExtKeyUsageAny)
+	serverCert := newTestCert(t, "localhost", ca, x509.ExtKeyUsageServerAuth)
+	clientCert := newTestCert(t, "tui-client", ca, x509.ExtKeyUsageClientAuth)
+
+	certPath, keyPath, caPath := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), filepath.Join(dir, "ca.pem")
+	writeFile(t, certPath, serverCert.certPEM)
+	writeFile(t, keyPath, serverCert.keyPEM)
+	writeFile(t, caPath, ca.certPEM)
+
+	certs, err := newCertReloader(certPath, keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.ClientCAPath = caPath
+	tlsConfig, err := newTLSConfig(cfg, certs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, clientSubject(r))
+	}))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0) // rejected handshakes are expected
+	srv.Listener = tls.NewListener(srv.Listener, tlsConfig)
+	srv.Start()
+	defer srv.Close()
+	url := "https://" + srv.Listener.Addr().String()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	get := func(clientCerts ...tls.Certificate) (string, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			Certificates: clientCerts,
+		}}}
+		defer client.CloseIdleConnections()
+		resp, err := client.Get(url)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	t.Run("without client certificate", func(t *testing.T) {
+		if _, err := get(); err == nil {
+			t.Fatal("request without a client certificate succeeded")
+		}
+	})
+
+	t.Run("with untrusted client certificate", func(t *testing.T) {
+		other := newTestCert(t, "other-ca", nil, x509.ExtKeyUsageAny)
+		rogue := newTestCert(t, "rogue", other, x509.ExtKeyUsageClientAuth)
+		if _, err := get(rogue.tlsCertificate(t)); err == nil {
+			t.Fatal("request with a certificate from another CA succeeded")
+		}
+	})
+
+	t.Run("with CA-signed client certificate", func(t *testing.T) {
+		subject, err := get(clientCert.tlsCertificate(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if subject != "CN=tui-client" {
+			t.Errorf("clientSubject = %q, want CN=tui-client", subject)
+		}
+	})
+}
+
+func TestCertReloaderKeepsCertOnInvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "test-ca", nil, x509.ExtKeyUsageAny)
+	first := newTestCert(t, "localhost", ca, x509.ExtKeyUsageServerAuth)
+
+	certPath, keyPath := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeFile(t, certPath, first.certPEM)
+	writeFile(t, keyPath, first.keyPEM)
+
+	certs, err := newCertReloader(certPath, keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A half-written rotation: new certificate, key still missing
+	second := newTestCert(t, "localhost", ca, x509.ExtKeyUsageServerAuth)
+	writeFile(t, certPath, second.certPEM)
+	writeFile(t, keyPath, []byte("not a key"))
+	if err := certs.reload(); err == nil {
+		t.Fatal("reload accepted an invalid key")
+	}
+	current, _ := certs.getCertificate(nil)
+	if !current.Leaf.Equal(first.cert) {
+		t.Fatal("invalid files replaced the current certificate")
+	}
+
+	// Completing the rotation swaps in the new certificate
+	writeFile(t, keyPath, second.keyPEM)
+	if err := certs.reload(); err != nil {
+		t.Fatal(err)
+	}
+	current, _ = certs.getCertificate(nil)
+	if !current.Leaf.Equal(second.cert) {
+		t.Fatal("reload did not swap in the new certificate")
+	}
+}
+
+func TestTLSWithoutClientCA(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "test-ca", nil, x509.ExtKeyUsageAny)
+	serverCert := newTestCert(t, "localhost", ca, x509.ExtKeyUsageServerAuth)
+	certPath, keyPath := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeFile(t, certPath, serverCert.certPEM)
+	writeFile(t, keyPath, serverCert.keyPEM)
+
+	s, _ := newTestServer(t, func(cfg *config.Config) {
+		cfg.TLSCertPath, cfg.TLSKeyPath = certPath, keyPath
+	})
+	if s.tlsConfig == nil {
+		t.Fatal("certificate and key configured without a client CA, but TLS is off")
+	}
+	if s.tlsConfig.ClientAuth != tls.NoClientCert {
+		t.Errorf("ClientAuth = %v, want NoClientCert", s.tlsConfig.ClientAuth)
+	}
+
+	srv := httptest.NewUnstartedServer(s.router)
+	srv.Listener = tls.NewListener(srv.Listener, s.tlsConfig)
+	srv.Start()
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	defer client.CloseIdleConnections()
+	resp, err := client.Get("https://" + srv.Listener.Addr().String() + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestCertReloadWithoutClientCA(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "test-ca", nil, x509.ExtKeyUsageAny)
+	first := newTestCert(t, "localhost", ca, x509.ExtKeyUsageServerAuth)
+	certPath, keyPath := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeFile(t, certPath, first.certPEM)
+	writeFile(t, keyPath, first.keyPEM)
+
+	s, _ := newTestServer(t, func(cfg *config.Config) {
+		cfg.TLSCertPath, cfg.TLSKeyPath = certPath, keyPath
+	})
+	srv := httptest.NewUnstartedServer(s.router)
+	srv.Listener = tls.NewListener(srv.Listener, s.tlsConfig)
+	srv.Start()
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	served := func() *x509.Certificate {
+		t.Helper()
+		conn, err := tls.Dial("tcp", srv.Listener.Addr().String(), &tls.Config{RootCAs: roots, ServerName: "localhost"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0]
+	}
+	if !served().Equal(first.cert) {
+		t.Fatal("not serving the configured certificate")
+	}
+
+	// SIGHUP path
+	second := newTestCert(t, "localhost", ca, x509.ExtKeyUsageServerAuth)
+	writeFile(t, certPath, second.certPEM)
+	writeFile(t, keyPath, second.keyPEM)
+	if err := s.ReloadCertificates(); err != nil {
+		t.Fatal(err)
+	}
+	if !served().Equal(second.cert) {
+		t.Fatal("ReloadCertificates did not swap in the new certificate")
+	}
+
+	// File watcher path, with the mtimes moved forward so the change is seen
+	third := newTestCert(t, "localhost", ca, x509.ExtKeyUsageServerAuth)
+	writeFile(t, certPath, third.certPEM)
+	writeFile(t, keyPath, third.keyPEM)
+	later := time.Now().Add(time.Minute)
+	for _, path := range []string{certPath, keyPath} {
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	go s.certs.watch(s.shutdownCtx, 10*time.Millisecond)
+	deadline := time.Now().Add(5 * time.Second)
+	for !served().Equal(third.cert) {
+		if time.Now().After(deadline) {
+			t.Fatal("watcher did not swap in the new certificate")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -3,11 +3,19 @@ package server
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/aniket/servertui/agent/internal/config"
 	"github.com/aniket/servertui/agent/internal/docker"
+	"github.com/aniket/servertui/agent/internal/services"
 	"github.com/gorilla/websocket"
 )
 
@@ -18,83 +26,351 @@ type AgentMessage struct {
 	Timestamp int64       `json:"timestamp"`
 }
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Allow all origins (configurable in production)
-	},
+// newUpgrader creates the WebSocket upgrader. With compression enabled,
+// permessage-deflate is negotiated with clients that offer it.
+func newUpgrader(cfg *config.Config) websocket.Upgrader {
+	return websocket.Upgrader{
+		ReadBufferSize:    cfg.WSReadBufferSize,
+		WriteBufferSize:   cfg.WSWriteBufferSize,
+		EnableCompression: cfg.WSCompression,
+		CheckOrigin: func(r *http.Request) bool {
+			return true // Allow all origins (configurable in production)
+		},
+	}
+}
+
+// wsConn wraps a WebSocket connection so that writes from multiple goroutines
+// are serialized; gorilla/websocket allows only one concurrent writer. Every
+// write method of the embedded connection that is used goes through writeMu.
+type wsConn struct {
+	*websocket.Conn
+	writeMu sync.Mutex
+	// writeTimeout bounds each write (0 disables)
+	writeTimeout time.Duration
+	// writeErr is the timeout that closed the connection; later writes return it
+	writeErr error
+}
+
+// newWSConn wraps an upgraded connection with the configured write timeout.
+func (s *Server) newWSConn(ws *websocket.Conn) *wsConn {
+	return &wsConn{Conn: ws, writeTimeout: s.config.WSWriteTimeout}
+}
+
+// WriteMessage writes a message while holding the connection's write lock.
+// A write that misses the deadline closes the connection, which also ends
+// the handler's read loop.
+func (c *wsConn) WriteMessage(messageType int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.writeErr != nil {
+		return c.writeErr
+	}
+	if c.writeTimeout > 0 {
+		c.Conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	}
+	err := c.Conn.WriteMessage(messageType, data)
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		slog.Warn("WebSocket write timed out, closing connection", "remote", c.RemoteAddr().String(), "timeout", c.writeTimeout)
+		c.Conn.Close()
+		c.writeErr = err
+	}
+	return err
+}
+
+// WriteJSON encodes v and writes it as a text message under the write lock.
+func (c *wsConn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(websocket.TextMessage, data)
+}
+
+// WriteControl writes a control frame such as a ping or close under the
+// write lock, so it never interleaves with a data frame being written.
+func (c *wsConn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.Conn.WriteControl(messageType, data, deadline)
+}
+
+// wsRegistry tracks active WebSocket connections so they can be drained on shutdown.
+// Hijacked connections are not waited for by http.Server.Shutdown.
+type wsRegistry struct {
+	mu      sync.Mutex
+	conns   map[*wsConn]struct{}
+	closing bool
+	wg      sync.WaitGroup
+}
+
+// add registers a connection. It returns false if the server is shutting down,
+// in which case the caller should close the connection immediately.
+func (reg *wsRegistry) add(conn *wsConn) bool {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	if reg.closing {
+		return false
+	}
+	if reg.conns == nil {
+		reg.conns = make(map[*wsConn]struct{})
+	}
+	reg.conns[conn] = struct{}{}
+	reg.wg.Add(1)
+	return true
+}
+
+// count returns the number of active connections.
+func (reg *wsRegistry) count() int {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	return len(reg.conns)
+}
+
+// remove unregisters a connection once its handler has finished.
+func (reg *wsRegistry) remove(conn *wsConn) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	if _, ok := reg.conns[conn]; ok {
+		delete(reg.conns, conn)
+		reg.wg.Done()
+	}
+}
+
+// closeAll stops accepting new connections and sends a close frame to every active one.
+// Frames are sent concurrently since each waits for its connection's write lock,
+// which a handler stuck on a slow client may hold until its write times out.
+func (reg *wsRegistry) closeAll() {
+	reg.mu.Lock()
+	reg.closing = true
+	conns := make([]*wsConn, 0, len(reg.conns))
+	for conn := range reg.conns {
+		conns = append(conns, conn)
+	}
+	reg.mu.Unlock()
+
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	for _, conn := range conns {
+		go conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+	}
+}
+
+// wait blocks until all handlers have finished or ctx expires.
+// Connections still open when ctx expires are closed forcibly.
+func (reg *wsRegistry) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		reg.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		reg.mu.Lock()
+		for conn := range reg.conns {
+			conn.Close()
+		}
+		reg.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Bounds for a client-requested metrics interval.
+const (
+	minClientMetricsInterval = 1 * time.Second
+	maxClientMetricsInterval = 5 * time.Minute
+)
+
+// metricsStream holds the per-connection settings of a metrics WebSocket.
+type metricsStream struct {
+	interval time.Duration
+	// clientInterval is set once the client picks an interval; config reloads then no longer apply
+	clientInterval bool
+	delta          bool
+	// last is the last frame sent, used for delta encoding
+	last map[string]any
+	// overrunning is set while collection takes longer than the interval
+	overrunning bool
+}
+
+// coalesceTicks keeps a stream whose last send took elapsed from firing
+// again straight away when that overran the interval: the pending tick is
+// dropped and the ticker restarted, so the missed ticks are skipped rather
+// than sent back to back. The first overrun of a streak is logged.
+func (ms *metricsStream) coalesceTicks(ticker *time.Ticker, elapsed time.Duration) {
+	if elapsed <= ms.interval {
+		if ms.overrunning {
+			slog.Info("Metrics collection caught up with the stream interval", "interval", ms.interval)
+			ms.overrunning = false
+		}
+		return
+	}
+
+	select {
+	case <-ticker.C:
+	default:
+	}
+	ticker.Reset(ms.interval)
+
+	skipped := int(elapsed / ms.interval)
+	if !ms.overrunning {
+		slog.Warn("Metrics collection is slower than the stream interval, skipping ticks",
+			"elapsed", elapsed, "interval", ms.interval, "skipped", skipped)
+		ms.overrunning = true
+	} else {
+		slog.Debug("Skipped metrics ticks", "elapsed", elapsed, "skipped", skipped)
+	}
+}
+
+// configure applies a "configure" message from the client.
+func (ms *metricsStream) configure(msg ClientMessage) error {
+	if msg.Action != "configure" {
+		return fmt.Errorf("unknown action: %s", msg.Action)
+	}
+
+	if msg.Interval != "" {
+		d, err := time.ParseDuration(msg.Interval)
+		if err != nil || d < minClientMetricsInterval || d > maxClientMetricsInterval {
+			return fmt.Errorf("interval must be a duration between %s and %s", minClientMetricsInterval, maxClientMetricsInterval)
+		}
+		ms.interval = d
+		ms.clientInterval = true
+	}
+	if msg.Delta != nil {
+		ms.delta = *msg.Delta
+		ms.last = nil // the next frame is a full snapshot
+	}
+	return nil
 }
 
 // handleMetricsWS handles the WebSocket connection for streaming metrics.
+// Clients may send {"action":"configure","interval":"5s","delta":true} at any
+// time to change the cadence or receive only the fields that changed.
 func (s *Server) handleMetricsWS(w http.ResponseWriter, r *http.Request) {
-	log.Printf("[WS] WebSocket connection attempt from: %s", r.RemoteAddr)
+	slog.Debug("Metrics WebSocket connection attempt", "remote", r.RemoteAddr)
 
-	conn, err := upgrader.Upgrade(w, r, nil)
+	ws, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("[WS] WebSocket upgrade failed: %v", err)
+		slog.Warn("WebSocket upgrade failed", "error", err)
 		return
 	}
-	defer conn.Close()
+	defer ws.Close()
 
-	log.Printf("[WS] WebSocket client connected: %s", r.RemoteAddr)
+	conn := s.newWSConn(ws)
+	if !s.wsConns.add(conn) {
+		return
+	}
+	defer s.wsConns.remove(conn)
+
+	slog.Info("Metrics WebSocket client connected", "remote", r.RemoteAddr)
 
 	// Create a ticker for sending metrics at the configured interval
-	log.Printf("[WS] Metrics interval: %v", s.config.MetricsInterval)
-	ticker := time.NewTicker(s.config.MetricsInterval)
+	stream := &metricsStream{interval: s.streamInterval()}
+	slog.Debug("Metrics streaming interval", "interval", stream.interval)
+	ticker := time.NewTicker(s.jittered(stream.interval))
 	defer ticker.Stop()
 
 	// Channel to signal when the client disconnects
 	done := make(chan struct{})
+	ctx, cancel := context.WithCancel(s.shutdownCtx)
+	defer cancel()
 
-	// Read loop to detect client disconnect
+	// Read loop to detect client disconnect and pass on client messages
+	messages := make(chan []byte)
 	go func() {
 		defer close(done)
 		for {
-			_, _, err := conn.ReadMessage()
+			_, data, err := conn.ReadMessage()
 			if err != nil {
 				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-					log.Printf("[WS] WebSocket read error: %v", err)
+					slog.Warn("WebSocket read error", "error", err)
 				}
 				return
 			}
+			select {
+			case messages <- data:
+			case <-ctx.Done():
+				return
+			}
 		}
 	}()
 
 	// Send initial metrics immediately
-	log.Println("[WS] Sending initial metrics...")
-	if err := s.sendMetrics(conn); err != nil {
-		log.Printf("[WS] Failed to send initial metrics: %v", err)
+	slog.Debug("Sending initial metrics")
+	if err := s.sendMetrics(conn, stream); err != nil {
+		slog.Warn("Failed to send initial metrics", "error", err)
 		return
 	}
-	log.Println("[WS] Initial metrics sent successfully")
+	slog.Debug("Initial metrics sent")
 
 	// Main loop: send metrics on each tick
 	for {
 		select {
 		case <-done:
-			log.Printf("[WS] WebSocket client disconnected: %s", r.RemoteAddr)
+			slog.Info("Metrics WebSocket client disconnected", "remote", r.RemoteAddr)
+			return
+		case <-ctx.Done():
 			return
+		case data := <-messages:
+			var msg ClientMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				s.sendWSMessage(conn, "error", map[string]string{"message": "Invalid message format"})
+				continue
+			}
+			if err := stream.configure(msg); err != nil {
+				s.sendWSMessage(conn, "error", map[string]string{"message": err.Error()})
+				continue
+			}
+			ticker.Reset(stream.interval)
+			slog.Debug("Metrics stream configured", "remote", r.RemoteAddr, "interval", stream.interval, "delta", stream.delta)
+			s.sendWSMessage(conn, "configured", map[string]any{
+				"interval": stream.interval.Milliseconds(),
+				"delta":    stream.delta,
+			})
 		case <-ticker.C:
-			log.Println("[WS] Ticker: sending metrics...")
-			if err := s.sendMetrics(conn); err != nil {
-				log.Printf("[WS] Failed to send metrics: %v", err)
+			s.rejitter(ticker, stream.interval)
+			slog.Debug("Ticker: sending metrics")
+			start := time.Now()
+			if err := s.sendMetrics(conn, stream); err != nil {
+				slog.Warn("Failed to send metrics", "error", err)
 				return
 			}
+			stream.coalesceTicks(ticker, time.Since(start))
+
+			// Pick up an interval changed by a config reload
+			if next := s.streamInterval(); !stream.clientInterval && next != stream.interval {
+				stream.interval = next
+				ticker.Reset(stream.interval)
+			}
 		}
 	}
 }
 
+// streamInterval returns the configured metrics interval. Validate rejects
+// intervals below config.MinMetricsInterval, but since time.NewTicker panics
+// on a non-positive interval it is enforced here too.
+func (s *Server) streamInterval() time.Duration {
+	return max(time.Duration(s.metricsInterval.Load()), config.MinMetricsInterval)
+}
+
 // sendMetrics collects and sends current metrics over the WebSocket.
-func (s *Server) sendMetrics(conn *websocket.Conn) error {
-	log.Println("[WS] Collecting metrics...")
+// With delta encoding on, frames after the first are "metricsDelta"
+// messages holding only the fields that changed.
+func (s *Server) sendMetrics(conn *wsConn, stream *metricsStream) error {
+	slog.Debug("Collecting metrics")
 	m, err := s.metricsCollector.GetMetrics()
 	if err != nil {
-		log.Printf("[WS] Failed to collect metrics: %v", err)
+		slog.Error("Failed to collect metrics", "error", err)
 		return err
 	}
 
-	log.Printf("[WS] Metrics collected: CPU=%.2f%%, Mem=%.2f%%", m.CPU.UsagePercent, m.Memory.UsagePercent)
+	slog.Debug("Metrics collected", "timestamp", m.Timestamp)
+	m.Instance = s.instance
 
 	msg := AgentMessage{
 		Type:      "metrics",
@@ -102,56 +378,317 @@ func (s *Server) sendMetrics(conn *websocket.Conn) error {
 		Timestamp: time.Now().UnixMilli(),
 	}
 
+	if stream.delta {
+		current, err := toJSONMap(m)
+		if err != nil {
+			slog.Error("Failed to encode metrics", "error", err)
+			return err
+		}
+		if stream.last != nil {
+			msg.Type = "metricsDelta"
+			msg.Data = jsonDiff(stream.last, current)
+		}
+		stream.last = current
+	}
+
 	data, err := json.Marshal(msg)
 	if err != nil {
-		log.Printf("[WS] Failed to marshal metrics: %v", err)
+		slog.Error("Failed to marshal metrics", "error", err)
 		return err
 	}
 
-	log.Printf("[WS] Sending %d bytes of metrics data", len(data))
+	slog.Debug("Sending metrics frame", "type", msg.Type, "bytes", len(data))
 	return conn.WriteMessage(websocket.TextMessage, data)
 }
 
+// handleDockerEventsWS streams container lifecycle events to the client.
+// The optional repeatable query parameters container and event narrow the
+// stream, e.g. ?container=web&event=die&event=oom.
+func (s *Server) handleDockerEventsWS(w http.ResponseWriter, r *http.Request) {
+	dm := s.dockerManager.Load()
+	if dm == nil {
+		http.Error(w, "Docker not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	filter := docker.EventFilter{
+		ContainerIDs: r.URL.Query()["container"],
+		Actions:      r.URL.Query()["event"],
+	}
+	if err := filter.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, id := range filter.ContainerIDs {
+		if !validContainerID(id) {
+			http.Error(w, "invalid container ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		slog.Warn("WebSocket upgrade failed", "error", err)
+		return
+	}
+	defer ws.Close()
+
+	conn := s.newWSConn(ws)
+	if !s.wsConns.add(conn) {
+		return
+	}
+	defer s.wsConns.remove(conn)
+
+	slog.Info("Docker events client connected", "remote", r.RemoteAddr)
+
+	// Canceling ctx ends the Docker events subscription
+	ctx, cancel := context.WithCancel(s.shutdownCtx)
+	defer cancel()
+
+	// Read loop to detect client disconnect
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	events := make(chan docker.Event, logBufferSize)
+	streamErr := make(chan error, 1)
+	go func() {
+		streamErr <- dm.StreamEvents(ctx, filter, events)
+	}()
+
+	for event := range events {
+		if err := s.sendWSMessage(conn, "dockerEvent", event); err != nil {
+			slog.Debug("Failed to send Docker event", "error", err)
+			cancel()
+		}
+	}
+
+	if err := <-streamErr; err != nil && ctx.Err() == nil {
+		slog.Warn("Docker events stream ended", "error", err)
+		s.sendWSMessage(conn, "error", map[string]string{"message": err.Error()})
+	}
+	slog.Info("Docker events client disconnected", "remote", r.RemoteAddr)
+}
+
+// alertBufferSize is the number of alert notifications buffered per subscriber.
+const alertBufferSize = 16
+
+// handleAlertsWS sends the current alerts on connect, then an "alert"
+// message each time an alert fires or resolves.
+func (s *Server) handleAlertsWS(w http.ResponseWriter, r *http.Request) {
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		slog.Warn("WebSocket upgrade failed", "error", err)
+		return
+	}
+	defer ws.Close()
+
+	conn := s.newWSConn(ws)
+	if !s.wsConns.add(conn) {
+		return
+	}
+	defer s.wsConns.remove(conn)
+
+	notifications, unsubscribe := s.alerts.Subscribe(alertBufferSize)
+	defer unsubscribe()
+
+	// Read loop to detect client disconnect
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	if err := s.sendWSMessage(conn, "alerts", s.alerts.Alerts()); err != nil {
+		return
+	}
+	for {
+		select {
+		case <-done:
+			return
+		case <-s.shutdownCtx.Done():
+			return
+		case n := <-notifications:
+			if err := s.sendWSMessage(conn, "alert", n); err != nil {
+				slog.Debug("Failed to send alert", "error", err)
+				return
+			}
+		}
+	}
+}
+
+// handleJournalWS follows the journal, sending a "journalEntry" message per
+// entry. It accepts the same unit, since and lines parameters as /api/logs/journal.
+func (s *Server) handleJournalWS(w http.ResponseWriter, r *http.Request) {
+	if !services.Available() {
+		http.Error(w, services.ErrNotAvailable.Error(), http.StatusNotImplemented)
+		return
+	}
+
+	opts, err := parseJournalOptions(r.URL.Query())
+	if err == nil {
+		err = opts.Validate()
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		slog.Warn("WebSocket upgrade failed", "error", err)
+		return
+	}
+	defer ws.Close()
+
+	conn := s.newWSConn(ws)
+	if !s.wsConns.add(conn) {
+		return
+	}
+	defer s.wsConns.remove(conn)
+
+	slog.Info("Journal client connected", "remote", r.RemoteAddr, "unit", opts.Unit)
+
+	// Canceling ctx stops journalctl
+	ctx, cancel := context.WithCancel(s.shutdownCtx)
+	defer cancel()
+
+	// Read loop to detect client disconnect
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	entries := make(chan services.JournalEntry, logBufferSize)
+	streamErr := make(chan error, 1)
+	go func() {
+		streamErr <- s.servicesManager.FollowJournal(ctx, opts, entries)
+	}()
+
+	for entry := range entries {
+		if err := s.sendWSMessage(conn, "journalEntry", entry); err != nil {
+			slog.Debug("Failed to send journal entry", "error", err)
+			cancel()
+		}
+	}
+
+	if err := <-streamErr; err != nil && ctx.Err() == nil {
+		slog.Warn("Journal stream ended", "error", err)
+		s.sendWSMessage(conn, "error", map[string]string{"message": err.Error()})
+	}
+	slog.Info("Journal client disconnected", "remote", r.RemoteAddr)
+}
+
 // ClientMessage represents a message from the client to the agent.
 type ClientMessage struct {
 	Action      string `json:"action"`
 	ContainerID string `json:"containerId,omitempty"`
+	// ContainerIDs lists the containers for startMultiLogs
+	ContainerIDs []string `json:"containerIds,omitempty"`
+	Since        string   `json:"since,omitempty"`
+	Until        string   `json:"until,omitempty"`
+	// TagStreams sends log lines as {stream, line} objects instead of plain strings
+	TagStreams bool `json:"tagStreams,omitempty"`
+	// Grep filters log lines as in /api/docker/containers/{id}/logs
+	Grep       string `json:"grep,omitempty"`
+	Regex      bool   `json:"regex,omitempty"`
+	IgnoreCase bool   `json:"ignoreCase,omitempty"`
+	Invert     bool   `json:"invert,omitempty"`
+	// Interval and Delta configure a metrics stream
+	Interval string `json:"interval,omitempty"`
+	Delta    *bool  `json:"delta,omitempty"`
+	// Command, Dir and Env start a /ws/exec session as in /api/exec, and
+	// Data carries its stdin
+	Command string            `json:"command,omitempty"`
+	Dir     string            `json:"dir,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	Data    string            `json:"data,omitempty"`
+}
+
+// logStream tracks the log stream running on a Docker logs connection.
+type logStream struct {
+	containerID string
+	cancel      context.CancelFunc
+	done        chan struct{}
+}
+
+// stop cancels the stream and waits for it to finish.
+func (ls *logStream) stop() {
+	ls.cancel()
+	<-ls.done
 }
 
 // handleDockerLogsWS handles WebSocket connections for streaming Docker container logs.
+// Log streaming runs in its own goroutine so the read loop keeps handling
+// client commands; starting a new stream replaces the active one.
 func (s *Server) handleDockerLogsWS(w http.ResponseWriter, r *http.Request) {
-	log.Printf("[WS] Docker logs WebSocket connection attempt from: %s", r.RemoteAddr)
+	slog.Debug("Docker logs WebSocket connection attempt", "remote", r.RemoteAddr)
 
-	if s.dockerManager == nil {
-		log.Println("[WS] Docker not available, rejecting connection")
+	dm := s.dockerManager.Load()
+	if dm == nil {
+		slog.Warn("Docker not available, rejecting logs WebSocket")
 		http.Error(w, "Docker not available", http.StatusServiceUnavailable)
 		return
 	}
 
-	conn, err := upgrader.Upgrade(w, r, nil)
+	ws, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("[WS] WebSocket upgrade failed: %v", err)
+		slog.Warn("WebSocket upgrade failed", "error", err)
+		return
+	}
+	defer ws.Close()
+
+	conn := s.newWSConn(ws)
+	if !s.wsConns.add(conn) {
 		return
 	}
-	defer conn.Close()
+	defer s.wsConns.remove(conn)
 
-	log.Printf("[WS] Docker logs client connected: %s", r.RemoteAddr)
+	slog.Info("Docker logs client connected", "remote", r.RemoteAddr)
+
+	// At most one of active and multi is running
+	var active *logStream
+	var multi *multiLogStream
+	stopAll := func() {
+		if active != nil {
+			active.stop()
+			active = nil
+		}
+		if multi != nil {
+			multi.stop()
+			multi = nil
+		}
+	}
+	defer stopAll()
 
 	// Read loop to handle client commands
 	for {
 		_, data, err := conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("[WS] WebSocket read error: %v", err)
+				slog.Warn("WebSocket read error", "error", err)
 			} else {
-				log.Printf("[WS] Client disconnected: %s", r.RemoteAddr)
+				slog.Info("Docker logs client disconnected", "remote", r.RemoteAddr)
 			}
 			return
 		}
 
 		var msg ClientMessage
 		if err := json.Unmarshal(data, &msg); err != nil {
-			log.Printf("[WS] Invalid message format: %v", err)
+			slog.Warn("Invalid WebSocket message format", "error", err)
 			s.sendWSMessage(conn, "error", map[string]string{"message": "Invalid message format"})
 			continue
 		}
@@ -162,6 +699,10 @@ func (s *Server) handleDockerLogsWS(w http.ResponseWriter, r *http.Request) {
 				s.sendWSMessage(conn, "error", map[string]string{"message": "Container ID required"})
 				continue
 			}
+			if !validContainerID(msg.ContainerID) {
+				s.sendWSMessage(conn, "error", map[string]string{"message": "Invalid container ID"})
+				continue
+			}
 			s.handleGetContainerDetails(conn, msg.ContainerID)
 
 		case "startLogs":
@@ -169,70 +710,194 @@ func (s *Server) handleDockerLogsWS(w http.ResponseWriter, r *http.Request) {
 				s.sendWSMessage(conn, "error", map[string]string{"message": "Container ID required"})
 				continue
 			}
-			s.handleStartLogsStreaming(conn, msg.ContainerID)
+			if !validContainerID(msg.ContainerID) {
+				s.sendWSMessage(conn, "error", map[string]string{"message": "Invalid container ID"})
+				continue
+			}
+			opts, err := followOptions(msg)
+			if err != nil {
+				s.sendWSMessage(conn, "error", map[string]string{"message": err.Error()})
+				continue
+			}
+
+			stopAll()
+			ctx, cancel := context.WithCancel(s.shutdownCtx)
+			active = &logStream{containerID: msg.ContainerID, cancel: cancel, done: make(chan struct{})}
+			go func(ls *logStream, containerID string, tagStreams bool) {
+				defer close(ls.done)
+				s.handleStartLogsStreaming(ctx, conn, containerID, opts, tagStreams)
+			}(active, msg.ContainerID, msg.TagStreams)
+
+		case "startMultiLogs":
+			if len(msg.ContainerIDs) == 0 {
+				s.sendWSMessage(conn, "error", map[string]string{"message": "Container IDs required"})
+				continue
+			}
+			if slices.ContainsFunc(msg.ContainerIDs, func(id string) bool { return !validContainerID(id) }) {
+				s.sendWSMessage(conn, "error", map[string]string{"message": "Invalid container ID"})
+				continue
+			}
+			opts, err := followOptions(msg)
+			if err != nil {
+				s.sendWSMessage(conn, "error", map[string]string{"message": err.Error()})
+				continue
+			}
+
+			stopAll()
+			multi = s.newMultiLogStream(conn, opts)
+			for _, id := range msg.ContainerIDs {
+				multi.add(id)
+			}
+
+		case "addContainer", "removeContainer":
+			if multi == nil {
+				s.sendWSMessage(conn, "error", map[string]string{"message": "No active multi-container log stream"})
+				continue
+			}
+			if !validContainerID(msg.ContainerID) {
+				s.sendWSMessage(conn, "error", map[string]string{"message": "Invalid container ID"})
+				continue
+			}
+			if msg.Action == "addContainer" {
+				multi.add(msg.ContainerID)
+			} else if !multi.remove(msg.ContainerID) {
+				s.sendWSMessage(conn, "error", map[string]string{"message": "Container not in log stream: " + msg.ContainerID})
+			}
+
+		case "stopLogs":
+			switch {
+			case active != nil:
+				s.sendWSMessage(conn, "logStreamStopped", map[string]string{"containerId": active.containerID})
+			case multi != nil:
+				s.sendWSMessage(conn, "logStreamStopped", map[string]string{})
+			default:
+				s.sendWSMessage(conn, "error", map[string]string{"message": "No active log stream"})
+				continue
+			}
+			stopAll()
 
 		default:
-			log.Printf("[WS] Unknown action: %s", msg.Action)
+			slog.Warn("Unknown WebSocket action", "action", msg.Action)
 			s.sendWSMessage(conn, "error", map[string]string{"message": "Unknown action: " + msg.Action})
 		}
 	}
 }
 
-// handleGetContainerDetails fetches and sends container details.
-func (s *Server) handleGetContainerDetails(conn *websocket.Conn, containerID string) {
-	log.Printf("[WS] Getting container details for: %s", containerID)
+// followOptions builds the options for following logs from a startLogs or
+// startMultiLogs message.
+func followOptions(msg ClientMessage) (docker.LogsOptions, error) {
+	opts := docker.LogsOptions{
+		Follow:     true,
+		Tail:       "100",
+		Timestamps: true,
+	}
+	if err := parseLogWindow(&opts, msg.Since, msg.Until); err != nil {
+		return opts, err
+	}
+	if msg.Grep != "" {
+		filter, err := docker.NewLogFilter(msg.Grep, msg.Regex, msg.IgnoreCase, msg.Invert)
+		if err != nil {
+			return opts, err
+		}
+		opts.Filter = filter
+	}
+	return opts, nil
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// handleGetContainerDetails fetches and sends container details.
+func (s *Server) handleGetContainerDetails(conn *wsConn, containerID string) {
+	slog.Debug("Getting container details", "container", containerID)
 
-	details, err := s.dockerManager.GetContainerDetails(ctx, containerID)
+	details, err := s.dockerManager.Load().GetContainerDetails(s.shutdownCtx, containerID)
 	if err != nil {
-		log.Printf("[WS] Failed to get container details: %v", err)
-		s.sendWSMessage(conn, "error", map[string]string{"message": err.Error()})
+		message := err.Error()
+		if docker.IsNotFound(err) {
+			message = fmt.Sprintf("container %q not found", containerID)
+		}
+		slog.Warn("Failed to get container details", "container", containerID, "error", err)
+		s.sendWSMessage(conn, "error", map[string]string{"message": message})
 		return
 	}
 
 	s.sendWSMessage(conn, "containerDetails", details)
 }
 
-// handleStartLogsStreaming starts streaming logs for a container.
-func (s *Server) handleStartLogsStreaming(conn *websocket.Conn, containerID string) {
-	log.Printf("[WS] Starting log streaming for container: %s", containerID)
+// logBufferSize bounds how many log lines may queue for a slow client
+// before further lines are dropped.
+const logBufferSize = 100
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+// handleStartLogsStreaming streams logs for a container until ctx is canceled,
+// the stream ends, or a write fails. With tagStreams set, each line is sent along with its source stream.
+// If the client cannot keep up, lines beyond logBufferSize are dropped and the
+// client is told how many were skipped via a logLinesDropped message.
+func (s *Server) handleStartLogsStreaming(ctx context.Context, conn *wsConn, containerID string, opts docker.LogsOptions, tagStreams bool) {
+	slog.Info("Starting log streaming", "container", containerID)
 
-	// Create a channel for log messages
-	logChan := make(chan string, 100)
-	defer close(logChan)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	// Start streaming in a goroutine
+	// Read from Docker in a goroutine that owns (and closes) the raw channel
+	raw := make(chan docker.LogLine)
 	go func() {
-		opts := docker.LogsOptions{
-			Follow:     true,
-			Tail:       "100",
-			Timestamps: true,
-		}
-		if err := s.dockerManager.StreamLogs(ctx, containerID, opts, logChan); err != nil {
+		defer close(raw)
+		if err := s.dockerManager.Load().StreamLogs(ctx, containerID, opts, raw); err != nil {
 			if err != context.Canceled {
-				log.Printf("[WS] Log streaming error: %v", err)
+				slog.Warn("Log streaming error", "container", containerID, "error", err)
 			}
 		}
 	}()
 
-	// Send logs to client
-	for logLine := range logChan {
-		if err := s.sendWSMessage(conn, "logLine", logLine); err != nil {
-			log.Printf("[WS] Failed to send log line: %v", err)
+	// Relay into a bounded buffer, dropping lines rather than blocking Docker reads
+	logChan := make(chan docker.LogLine, logBufferSize)
+	var dropped atomic.Int64
+	go func() {
+		defer close(logChan)
+		for line := range raw {
+			select {
+			case logChan <- line:
+			default:
+				dropped.Add(1)
+			}
+		}
+	}()
+
+	// Send logs to client until canceled, the stream ends, or a write fails
+	for {
+		var logLine docker.LogLine
+		var ok bool
+		select {
+		case <-ctx.Done():
+			slog.Info("Log streaming stopped", "container", containerID)
+			return
+		case logLine, ok = <-logChan:
+		}
+		if !ok {
+			break
+		}
+
+		if n := dropped.Swap(0); n > 0 {
+			slog.Debug("Dropped log lines for slow client", "container", containerID, "count", n)
+			if err := s.sendWSMessage(conn, "logLinesDropped", map[string]int64{"count": n}); err != nil {
+				slog.Warn("Failed to send log line", "error", err)
+				return
+			}
+		}
+
+		var data interface{} = logLine.Line
+		if tagStreams {
+			data = logLine
+		}
+		if err := s.sendWSMessage(conn, "logLine", data); err != nil {
+			slog.Warn("Failed to send log line", "error", err)
 			return
 		}
 	}
 
-	log.Printf("[WS] Log streaming ended for container: %s", containerID)
+	slog.Info("Log streaming ended", "container", containerID)
 }
 
 // sendWSMessage sends a message over WebSocket.
-func (s *Server) sendWSMessage(conn *websocket.Conn, msgType string, data interface{}) error {
+func (s *Server) sendWSMessage(conn *wsConn, msgType string, data interface{}) error {
 	msg := AgentMessage{
 		Type:      msgType,
 		Data:      data,
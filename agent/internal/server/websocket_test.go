@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aniket/servertui/agent/internal/config"
+	"github.com/gorilla/websocket"
+)
+
+func TestMetricsWSCompression(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		server, client bool
+	}{
+		{"both enabled", true, true},
+		{"server only", true, false},
+		{"client only", false, true},
+		{"both disabled", false, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, srv := newTestServer(t, func(cfg *config.Config) { cfg.WSCompression = tc.server })
+
+			dialer := websocket.Dialer{EnableCompression: tc.client, HandshakeTimeout: 5 * time.Second}
+			ws, resp, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws/metrics", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ws.Close()
+			ws.SetReadDeadline(time.Now().Add(10 * time.Second))
+
+			negotiated := strings.Contains(resp.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate")
+			if want := tc.server && tc.client; negotiated != want {
+				t.Errorf("permessage-deflate negotiated = %v, want %v", negotiated, want)
+			}
+
+			// Server to client: the initial metrics frame
+			var msg AgentMessage
+			if err := ws.ReadJSON(&msg); err != nil {
+				t.Fatal(err)
+			}
+			if msg.Type != "metrics" {
+				t.Fatalf("first message type = %q, want metrics", msg.Type)
+			}
+			if data, _ := msg.Data.(map[string]any); data["cpu"] == nil {
+				t.Errorf("metrics frame has no cpu field: %v", msg.Data)
+			}
+
+			// Client to server: a configure message is answered
+			if err := ws.WriteJSON(ClientMessage{Action: "configure", Interval: "2s"}); err != nil {
+				t.Fatal(err)
+			}
+			for {
+				var reply struct {
+					Type string          `json:"type"`
+					Data json.RawMessage `json:"data"`
+				}
+				if err := ws.ReadJSON(&reply); err != nil {
+					t.Fatal(err)
+				}
+				if reply.Type == "metrics" {
+					continue
+				}
+				if reply.Type != "configured" {
+					t.Fatalf("reply type = %q (%s), want configured", reply.Type, reply.Data)
+				}
+				break
+			}
+		})
+	}
+}
+
+func TestMetricsStreamConfigureInterval(t *testing.T) {
+	for _, tc := range []struct {
+		interval string
+		wantErr  bool
+	}{
+		{"0s", true},
+		{"-5s", true},
+		{"500ms", true},
+		{"10m", true},
+		{"soon", true},
+		{"1s", false},
+		{"5m", false},
+	} {
+		ms := &metricsStream{interval: 2 * time.Second}
+		err := ms.configure(ClientMessage{Action: "configure", Interval: tc.interval})
+		if (err != nil) != tc.wantErr {
+			t.Errorf("configure(%q) error = %v, want error %v", tc.interval, err, tc.wantErr)
+		}
+		if tc.wantErr && (ms.interval != 2*time.Second || ms.clientInterval) {
+			t.Errorf("rejected interval %q changed the stream to %v", tc.interval, ms.interval)
+		}
+	}
+}
+
+func TestStreamIntervalClamps(t *testing.T) {
+	var s Server
+	for _, stored := range []time.Duration{0, -time.Second, config.MinMetricsInterval / 2} {
+		s.metricsInterval.Store(int64(stored))
+		if got := s.streamInterval(); got != config.MinMetricsInterval {
+			t.Errorf("streamInterval() with %v = %v, want %v", stored, got, config.MinMetricsInterval)
+		}
+	}
+	s.metricsInterval.Store(int64(3 * time.Second))
+	if got := s.streamInterval(); got != 3*time.Second {
+		t.Errorf("streamInterval() = %v, want 3s", got)
+	}
+}
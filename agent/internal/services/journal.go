@@ -0,0 +1,230 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Journal query limits.
+const (
+	DefaultJournalLines = 100
+	MaxJournalLines     = 5000
+
+	// maxJournalEntryBytes bounds a single JSON entry read from journalctl
+	maxJournalEntryBytes = 1 << 20
+)
+
+// ErrInvalidLines is returned for a line count outside 1..MaxJournalLines.
+var ErrInvalidLines = fmt.Errorf("lines must be between 1 and %d", MaxJournalLines)
+
+// JournalEntry is a single journald log entry.
+type JournalEntry struct {
+	Time       int64  `json:"time"` // unix milliseconds
+	Unit       string `json:"unit,omitempty"`
+	Identifier string `json:"identifier,omitempty"`
+	PID        int    `json:"pid,omitempty"`
+	Priority   int    `json:"priority"` // syslog priority, 0 (emerg) to 7 (debug)
+	Message    string `json:"message"`
+}
+
+// JournalOptions selects journal entries. An empty Unit matches every unit
+// and a zero Since applies no time bound.
+type JournalOptions struct {
+	Unit  string
+	Since time.Time
+	Lines int
+}
+
+// args builds the journalctl arguments for opts. The unit must already be
+// normalized.
+func (opts JournalOptions) args(follow bool) []string {
+	args := []string{"--output=json", "--no-pager", "--lines=" + strconv.Itoa(opts.Lines)}
+	if opts.Unit != "" {
+		args = append(args, "--unit="+opts.Unit)
+	}
+	if !opts.Since.IsZero() {
+		args = append(args, "--since=@"+strconv.FormatInt(opts.Since.Unix(), 10))
+	}
+	if follow {
+		args = append(args, "--follow")
+	}
+	return args
+}
+
+// Validate normalizes the unit and applies the default line count.
+func (opts *JournalOptions) Validate() error {
+	if opts.Unit != "" {
+		unit, err := NormalizeUnit(opts.Unit)
+		if err != nil {
+			return err
+		}
+		opts.Unit = unit
+	}
+	if opts.Lines == 0 {
+		opts.Lines = DefaultJournalLines
+	}
+	if opts.Lines < 1 || opts.Lines > MaxJournalLines {
+		return ErrInvalidLines
+	}
+	return nil
+}
+
+// Journal returns the most recent journal entries matching opts, oldest first.
+func (m *Manager) Journal(ctx context.Context, opts JournalOptions) ([]JournalEntry, error) {
+	if !Available() {
+		return nil, ErrNotAvailable
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	if m.commandTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.commandTimeout)
+		defer cancel()
+	}
+
+	entries := []JournalEntry{}
+	err := m.journalctl(ctx, opts.args(false), func(e JournalEntry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// FollowJournal sends the last opts.Lines entries matching opts to entryChan,
+// then new entries as they are written, until ctx is canceled or journalctl
+// exits. It closes entryChan when done.
+func (m *Manager) FollowJournal(ctx context.Context, opts JournalOptions, entryChan chan<- JournalEntry) error {
+	defer close(entryChan)
+
+	if !Available() {
+		return ErrNotAvailable
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	return m.journalctl(ctx, opts.args(true), func(e JournalEntry) error {
+		select {
+		case entryChan <- e:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// journalctl runs journalctl with args and calls fn for each entry it prints.
+func (m *Manager) journalctl(ctx context.Context, args []string, fn func(JournalEntry) error) error {
+	cmd := exec.CommandContext(ctx, "journalctl", args...)
+	cmd.WaitDelay = 2 * time.Second
+
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("journalctl: %w", err)
+	}
+
+	readErr := readJournal(stdout, fn)
+	if readErr != nil {
+		// Stop journalctl so Wait doesn't block on a follow
+		cmd.Process.Kill()
+	}
+	waitErr := cmd.Wait()
+
+	if readErr != nil {
+		return readErr
+	}
+	if waitErr != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("journalctl: %s", msg)
+		}
+		return fmt.Errorf("journalctl: %w", waitErr)
+	}
+	return nil
+}
+
+// readJournal decodes journalctl --output=json lines from r.
+func readJournal(r io.Reader, fn func(JournalEntry) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxJournalEntryBytes)
+	for scanner.Scan() {
+		entry, err := parseJournalEntry(scanner.Bytes())
+		if err != nil {
+			continue
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, io.ErrClosedPipe) {
+		return fmt.Errorf("reading journal: %w", err)
+	}
+	return nil
+}
+
+// parseJournalEntry converts one JSON journal record. journald encodes field
+// values as strings, or as byte arrays when they are not valid UTF-8.
+func parseJournalEntry(line []byte) (JournalEntry, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return JournalEntry{}, err
+	}
+
+	entry := JournalEntry{
+		Unit:       journalField(fields, "_SYSTEMD_UNIT"),
+		Identifier: journalField(fields, "SYSLOG_IDENTIFIER"),
+		Message:    journalField(fields, "MESSAGE"),
+		Priority:   6, // journald's default (info) when unset
+	}
+	if usec, err := strconv.ParseInt(journalField(fields, "__REALTIME_TIMESTAMP"), 10, 64); err == nil {
+		entry.Time = usec / 1000
+	}
+	if pid, err := strconv.Atoi(journalField(fields, "_PID")); err == nil {
+		entry.PID = pid
+	}
+	if prio, err := strconv.Atoi(journalField(fields, "PRIORITY")); err == nil {
+		entry.Priority = prio
+	}
+	return entry, nil
+}
+
+// journalField returns a field as a string, decoding byte-array values.
+func journalField(fields map[string]json.RawMessage, name string) string {
+	raw, ok := fields[name]
+	if !ok {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var ints []int
+	if err := json.Unmarshal(raw, &ints); err == nil {
+		b := make([]byte, len(ints))
+		for i, v := range ints {
+			b[i] = byte(v)
+		}
+		return strings.ToValidUTF8(string(b), "�")
+	}
+	return ""
+}
@@ -0,0 +1,151 @@
+// Package services reports and controls systemd services.
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotAvailable is returned when the host is not running systemd.
+	ErrNotAvailable = errors.New("systemd is not available on this host")
+
+	// ErrInvalidUnit is returned when a unit name contains unexpected characters.
+	ErrInvalidUnit = errors.New("invalid unit name")
+
+	// ErrInvalidAction is returned for actions other than start, stop and restart.
+	ErrInvalidAction = errors.New("action must be start, stop or restart")
+)
+
+// unitPattern matches systemd unit names, including template instances.
+var unitPattern = regexp.MustCompile(`^[A-Za-z0-9:_.@\\-]+$`)
+
+// Service is a systemd service unit.
+type Service struct {
+	Unit        string `json:"unit"`
+	Load        string `json:"load"`
+	Active      string `json:"active"`
+	Sub         string `json:"sub"`
+	Description string `json:"description"`
+}
+
+// Manager queries and controls systemd services.
+type Manager struct {
+	commandTimeout time.Duration
+}
+
+// NewManager creates a service manager. commandTimeout bounds each
+// systemctl invocation (0 disables the limit).
+func NewManager(commandTimeout time.Duration) *Manager {
+	return &Manager{commandTimeout: commandTimeout}
+}
+
+// Available reports whether the host was booted with systemd.
+func Available() bool {
+	_, err := os.Stat("/run/systemd/system")
+	return err == nil
+}
+
+// NormalizeUnit validates a unit name and adds the .service suffix if missing.
+func NormalizeUnit(name string) (string, error) {
+	if name == "" || strings.HasPrefix(name, "-") || !unitPattern.MatchString(name) {
+		return "", ErrInvalidUnit
+	}
+	if !strings.Contains(name, ".") {
+		name += ".service"
+	}
+	return name, nil
+}
+
+// ControlCommand returns the command line that Control runs, for checking
+// against the exec policy. The unit must already be normalized.
+func ControlCommand(action, unit string) (string, error) {
+	switch action {
+	case "start", "stop", "restart":
+		return "systemctl " + action + " " + unit, nil
+	}
+	return "", ErrInvalidAction
+}
+
+// List returns every service unit systemd knows about.
+func (m *Manager) List(ctx context.Context) ([]Service, error) {
+	if !Available() {
+		return nil, ErrNotAvailable
+	}
+
+	out, err := m.systemctl(ctx, "list-units", "--type=service", "--all", "--no-pager", "--output=json")
+	if err == nil {
+		var services []Service
+		if jsonErr := json.Unmarshal(out, &services); jsonErr == nil {
+			return services, nil
+		}
+	}
+
+	// systemd before v246 has no JSON output
+	out, err = m.systemctl(ctx, "list-units", "--type=service", "--all", "--no-pager", "--plain", "--no-legend")
+	if err != nil {
+		return nil, err
+	}
+	return parsePlain(string(out)), nil
+}
+
+// parsePlain parses "UNIT LOAD ACTIVE SUB DESCRIPTION" lines.
+func parsePlain(output string) []Service {
+	services := []Service{}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		services = append(services, Service{
+			Unit:        fields[0],
+			Load:        fields[1],
+			Active:      fields[2],
+			Sub:         fields[3],
+			Description: strings.Join(fields[4:], " "),
+		})
+	}
+	return services
+}
+
+// Control runs a start, stop or restart action on a normalized unit.
+func (m *Manager) Control(ctx context.Context, action, unit string) error {
+	if !Available() {
+		return ErrNotAvailable
+	}
+	if _, err := ControlCommand(action, unit); err != nil {
+		return err
+	}
+	_, err := m.systemctl(ctx, action, unit)
+	return err
+}
+
+// systemctl runs systemctl with args, returning stdout. Failures include stderr.
+func (m *Manager) systemctl(ctx context.Context, args ...string) ([]byte, error) {
+	if m.commandTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.commandTimeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, "systemctl", args...)
+	cmd.WaitDelay = 2 * time.Second
+
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("systemctl %s: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("systemctl %s: %w", args[0], err)
+	}
+	return out, nil
+}
@@ -0,0 +1,84 @@
+package updates
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// updatesCache holds the result of the last update check.
+type updatesCache struct {
+	ttl time.Duration
+
+	// refreshMu serializes checks so concurrent callers share one
+	refreshMu sync.Mutex
+
+	mu        sync.Mutex
+	updates   []PackageUpdate
+	checkedAt time.Time
+	// generation is bumped on invalidation so a check that overlapped a
+	// package change isn't stored
+	generation uint64
+}
+
+// get returns the cached check if it is still fresh. With keep set it is
+// fresh until replaced or invalidated, whatever its age.
+func (c *updatesCache) get(keep bool) ([]PackageUpdate, time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.checkedAt.IsZero() || (!keep && (c.ttl <= 0 || time.Since(c.checkedAt) >= c.ttl)) {
+		return nil, time.Time{}, false
+	}
+	return slices.Clone(c.updates), c.checkedAt, true
+}
+
+// CachedUpdates returns the available updates, reusing the last check if it
+// is younger than the cache TTL (or the background refresher is running) and
+// refresh is false. It also returns when that check ran.
+func (m *Manager) CachedUpdates(ctx context.Context, refresh bool) ([]PackageUpdate, time.Time, error) {
+	c := &m.cache
+	if !refresh {
+		if updates, checkedAt, ok := c.get(m.background.Load()); ok {
+			return updates, checkedAt, nil
+		}
+	}
+
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	// Another caller may have checked while we waited
+	if !refresh {
+		if updates, checkedAt, ok := c.get(m.background.Load()); ok {
+			return updates, checkedAt, nil
+		}
+	}
+
+	c.mu.Lock()
+	generation := c.generation
+	c.mu.Unlock()
+
+	checkedAt := time.Now()
+	updates, err := m.GetUpdates(ctx)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	c.mu.Lock()
+	if (c.ttl > 0 || m.background.Load()) && c.generation == generation {
+		c.updates = updates
+		c.checkedAt = checkedAt
+	}
+	c.mu.Unlock()
+	return slices.Clone(updates), checkedAt, nil
+}
+
+// InvalidateUpdates discards the cached update check, so the next call to
+// CachedUpdates checks again. Call it after packages change.
+func (m *Manager) InvalidateUpdates() {
+	c := &m.cache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.updates = nil
+	c.checkedAt = time.Time{}
+	c.generation++
+}
@@ -0,0 +1,68 @@
+package updates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+)
+
+// ErrInvalidPackageName is returned for names that aren't plain package names.
+var ErrInvalidPackageName = errors.New("invalid package name")
+
+// packageNamePattern accepts Debian, RPM and apk package names, optionally
+// with an architecture suffix. The leading character keeps a name from being
+// parsed as an option.
+var packageNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.+_@:-]{0,127}$`)
+
+// ValidatePackageName checks that name is safe to pass to the package manager.
+func ValidatePackageName(name string) error {
+	if !packageNamePattern.MatchString(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidPackageName, name)
+	}
+	return nil
+}
+
+// InstallPackage installs a package that isn't necessarily installed yet.
+func (m *Manager) InstallPackage(ctx context.Context, packageName string) (*CommandResult, error) {
+	if err := ValidatePackageName(packageName); err != nil {
+		return nil, err
+	}
+
+	slog.Info("Installing package", "package", packageName, "distro", m.distro)
+	defer m.InvalidateUpdates()
+	switch m.distro {
+	case DistroDebian, DistroUbuntu:
+		return m.executeCommand(ctx, "apt-get", "install", "-y", "--", packageName)
+	case DistroRHEL, DistroCentOS, DistroFedora:
+		return m.executeCommand(ctx, "yum", "install", "-y", "--", packageName)
+	case DistroAlpine:
+		return m.executeCommand(ctx, "apk", "add", "--", packageName)
+	default:
+		slog.Error("Unsupported distribution", "distro", m.distro)
+		return nil, fmt.Errorf("unsupported distribution: %s", m.distro)
+	}
+}
+
+// RemovePackage uninstalls a package, keeping its configuration files where
+// the package manager distinguishes them.
+func (m *Manager) RemovePackage(ctx context.Context, packageName string) (*CommandResult, error) {
+	if err := ValidatePackageName(packageName); err != nil {
+		return nil, err
+	}
+
+	slog.Info("Removing package", "package", packageName, "distro", m.distro)
+	defer m.InvalidateUpdates()
+	switch m.distro {
+	case DistroDebian, DistroUbuntu:
+		return m.executeCommand(ctx, "apt-get", "remove", "-y", "--", packageName)
+	case DistroRHEL, DistroCentOS, DistroFedora:
+		return m.executeCommand(ctx, "yum", "remove", "-y", "--", packageName)
+	case DistroAlpine:
+		return m.executeCommand(ctx, "apk", "del", "--", packageName)
+	default:
+		slog.Error("Unsupported distribution", "distro", m.distro)
+		return nil, fmt.Errorf("unsupported distribution: %s", m.distro)
+	}
+}
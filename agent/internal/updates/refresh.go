@@ -0,0 +1,108 @@
+package updates
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// RefreshStatus reports on the background package index refresher.
+type RefreshStatus struct {
+	Enabled     bool       `json:"enabled"`
+	Interval    string     `json:"interval,omitempty"`
+	LastRefresh *time.Time `json:"lastRefresh,omitempty"`
+	Duration    int64      `json:"duration,omitempty"` // milliseconds
+	OK          bool       `json:"ok"`
+	Error       string     `json:"error,omitempty"`
+	Updates     int        `json:"updates"` // available updates found by the last refresh
+}
+
+// RunRefresher refreshes the package index every interval until ctx is
+// canceled, checking for updates after each refresh. While it runs, update
+// checks use the local index and cached results stay valid until the next
+// refresh, so requests never wait on the network.
+func (m *Manager) RunRefresher(ctx context.Context, interval time.Duration) {
+	m.refresh.mu.Lock()
+	m.refresh.status = RefreshStatus{Enabled: true, Interval: interval.String()}
+	m.refresh.mu.Unlock()
+	m.background.Store(true)
+	defer m.background.Store(false)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		m.refreshOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RefreshStatus returns the outcome of the last background refresh.
+func (m *Manager) RefreshStatus() RefreshStatus {
+	m.refresh.mu.Lock()
+	defer m.refresh.mu.Unlock()
+	return m.refresh.status
+}
+
+// refreshOnce updates the package index and the cached update check,
+// recording the outcome.
+func (m *Manager) refreshOnce(ctx context.Context) {
+	start := time.Now()
+	err := m.refreshIndex(ctx)
+	var count int
+	if err == nil {
+		var updates []PackageUpdate
+		updates, _, err = m.CachedUpdates(ctx, true)
+		count = len(updates)
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		slog.Warn("Background package refresh failed", "error", err)
+	} else {
+		slog.Debug("Background package refresh done", "updates", count)
+	}
+
+	m.refresh.mu.Lock()
+	defer m.refresh.mu.Unlock()
+	s := &m.refresh.status
+	s.LastRefresh = &start
+	s.Duration = time.Since(start).Milliseconds()
+	s.OK = err == nil
+	s.Error = ""
+	if err != nil {
+		s.Error = err.Error()
+	}
+	s.Updates = count
+}
+
+// refreshIndex downloads the latest package lists.
+func (m *Manager) refreshIndex(ctx context.Context) error {
+	var result *CommandResult
+	var err error
+	switch m.distro {
+	case DistroDebian, DistroUbuntu:
+		result, err = m.executeCommand(ctx, "apt-get", "update", "-qq")
+	case DistroRHEL, DistroCentOS, DistroFedora:
+		result, err = m.executeCommand(ctx, "yum", "makecache", "-q")
+	case DistroAlpine:
+		result, err = m.executeCommand(ctx, "apk", "update", "-q")
+	default:
+		return fmt.Errorf("unsupported distribution: %s", m.distro)
+	}
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("package index refresh failed: %s", strings.TrimSpace(result.Stderr))
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package updates
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// MaxSearchResults caps the number of packages SearchPackages returns.
+const MaxSearchResults = 100
+
+// ErrInvalidQuery is returned for search queries containing characters other
+// than those allowed in package names.
+var ErrInvalidQuery = errors.New("invalid search query")
+
+// PackageInfo is a package matched by a search.
+type PackageInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// SearchPackages searches the package manager's index for query, returning
+// at most MaxSearchResults matches.
+func (m *Manager) SearchPackages(ctx context.Context, query string) ([]PackageInfo, error) {
+	// The query is matched as a package name pattern by apt-cache, so the same
+	// character set keeps it from being read as an option or a complex regex
+	if !packageNamePattern.MatchString(query) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidQuery, query)
+	}
+
+	var result *CommandResult
+	var err error
+	var parse func(string) []PackageInfo
+
+	switch m.distro {
+	case DistroDebian, DistroUbuntu:
+		result, err = m.executeCommand(ctx, "apt-cache", "search", "--", query)
+		parse = parseAptSearch
+	case DistroRHEL, DistroCentOS, DistroFedora:
+		result, err = m.executeCommand(ctx, "yum", "search", "-q", "--", query)
+		parse = parseYumSearch
+	case DistroAlpine:
+		result, err = m.executeCommand(ctx, "apk", "search", "-v", "--", query)
+		parse = parseApkSearch
+	default:
+		slog.Error("Unsupported distribution", "distro", m.distro)
+		return nil, fmt.Errorf("unsupported distribution: %s", m.distro)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("package search failed: %s", strings.TrimSpace(result.Stderr))
+	}
+
+	pkgs := parse(result.Stdout)
+	if len(pkgs) > MaxSearchResults {
+		pkgs = pkgs[:MaxSearchResults]
+	}
+	return pkgs, nil
+}
+
+// parseAptSearch parses apt-cache search output.
+// Example: htop - interactive processes viewer
+func parseAptSearch(output string) []PackageInfo {
+	return parseSearchLines(output, " - ", func(name string) string { return name })
+}
+
+// parseYumSearch parses yum search output, skipping the section headers.
+// Example: htop.x86_64 : Interactive process viewer
+func parseYumSearch(output string) []PackageInfo {
+	return parseSearchLines(output, " : ", func(name string) string {
+		// Drop the architecture suffix
+		if i := strings.LastIndex(name, "."); i > 0 {
+			return name[:i]
+		}
+		return name
+	})
+}
+
+// parseApkSearch parses apk search -v output.
+// Example: htop-3.2.2-r1 - Interactive process viewer
+func parseApkSearch(output string) []PackageInfo {
+	return parseSearchLines(output, " - ", func(name string) string {
+		name, _ = splitPackageVersion(name)
+		return name
+	})
+}
+
+// parseSearchLines splits each "name<sep>description" line, cleaning the name
+// with clean. Lines without sep or whose name has spaces are ignored.
+func parseSearchLines(output, sep string, clean func(string) string) []PackageInfo {
+	pkgs := []PackageInfo{}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		name, desc, ok := strings.Cut(scanner.Text(), sep)
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			continue
+		}
+		pkgs = append(pkgs, PackageInfo{Name: clean(name), Description: strings.TrimSpace(desc)})
+	}
+	return pkgs
+}
@@ -0,0 +1,148 @@
+package updates
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"time"
+)
+
+// maxOutputLineBytes bounds a streamed output line; longer lines are split.
+const maxOutputLineBytes = 64 * 1024
+
+// OutputLine is a line of output from a streamed command.
+type OutputLine struct {
+	Stream string `json:"stream"` // stdout or stderr
+	Line   string `json:"line"`
+}
+
+// StreamCommand runs a shell command like ExecuteCommand, but sends its output
+// to out line by line as it is written instead of buffering it. Data received
+// on stdin is written to the command's standard input, which is closed when
+// stdin is closed; a nil stdin gives the command no input. out is closed when
+// the command exits. The returned result has no Stdout or Stderr.
+func (m *Manager) StreamCommand(ctx context.Context, command string, opts ExecOptions, stdin <-chan []byte, out chan<- OutputLine) (*CommandResult, error) {
+	defer close(out)
+
+	if m.commandTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.commandTimeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	// Don't wait forever on pipes held open by orphaned grandchildren after a kill
+	cmd.WaitDelay = 2 * time.Second
+	cmd.Dir = opts.Dir
+	cmd.Env = opts.environ()
+
+	stdout := &lineWriter{ctx: ctx, stream: "stdout", out: out}
+	stderr := &lineWriter{ctx: ctx, stream: "stderr", out: out}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	var stdinPipe io.WriteCloser
+	if stdin != nil {
+		var err error
+		if stdinPipe, err = cmd.StdinPipe(); err != nil {
+			return nil, err
+		}
+	}
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return &CommandResult{ExitCode: -1, Stderr: err.Error()}, nil
+	}
+
+	exited := make(chan struct{})
+	if stdinPipe != nil {
+		go feedStdin(stdinPipe, stdin, exited)
+	}
+
+	err := cmd.Wait()
+	close(exited)
+	stdout.flush()
+	stderr.flush()
+
+	result := &CommandResult{Duration: time.Since(start).Milliseconds()}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		slog.Error("Command timed out", "command", "sh", "timeout", m.commandTimeout)
+		result.ExitCode = -1
+		result.TimedOut = true
+		return result, fmt.Errorf("%w after %v", ErrCommandTimeout, m.commandTimeout)
+	}
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			result.ExitCode = exitErr.ExitCode()
+		} else {
+			result.ExitCode = -1
+			result.Stderr = err.Error()
+		}
+	}
+	return result, nil
+}
+
+// feedStdin copies data from stdin to the command until stdin is closed or
+// the command exits, then closes the pipe.
+func feedStdin(pipe io.WriteCloser, stdin <-chan []byte, exited <-chan struct{}) {
+	defer pipe.Close()
+	for {
+		select {
+		case <-exited:
+			return
+		case data, ok := <-stdin:
+			if !ok {
+				return
+			}
+			if _, err := pipe.Write(data); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// lineWriter sends each line written to it to out, tagged with stream.
+// Lines longer than maxOutputLineBytes are split. If ctx is canceled lines are
+// dropped rather than blocking the command.
+type lineWriter struct {
+	ctx    context.Context
+	stream string
+	out    chan<- OutputLine
+	buf    []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.send(bytes.TrimSuffix(w.buf[:i], []byte("\r")))
+		w.buf = w.buf[i+1:]
+	}
+	for len(w.buf) >= maxOutputLineBytes {
+		w.send(w.buf[:maxOutputLineBytes])
+		w.buf = w.buf[maxOutputLineBytes:]
+	}
+	return len(p), nil
+}
+
+// flush sends a trailing line that had no newline.
+func (w *lineWriter) flush() {
+	if len(w.buf) > 0 {
+		w.send(w.buf)
+		w.buf = nil
+	}
+}
+
+func (w *lineWriter) send(line []byte) {
+	select {
+	case w.out <- OutputLine{Stream: w.stream, Line: string(line)}:
+	case <-w.ctx.Done():
+	}
+}
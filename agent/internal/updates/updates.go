@@ -3,13 +3,20 @@ package updates
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"maps"
 	"os"
 	"os/exec"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -19,6 +26,9 @@ type PackageUpdate struct {
 	CurrentVersion string `json:"currentVersion"`
 	NewVersion     string `json:"newVersion"`
 	Repository     string `json:"repository,omitempty"`
+	// Held is set for packages pinned with apt-mark hold, yum versionlock or
+	// an apk world constraint; upgrading them is refused
+	Held bool `json:"held"`
 }
 
 // CommandResult contains the result of a command execution.
@@ -27,8 +37,16 @@ type CommandResult struct {
 	Stderr   string `json:"stderr"`
 	ExitCode int    `json:"exitCode"`
 	Duration int64  `json:"duration"` // milliseconds
+	TimedOut bool   `json:"timedOut,omitempty"`
 }
 
+// ErrPackageHeld is returned when asked to upgrade a held package.
+var ErrPackageHeld = errors.New("package is held")
+
+// ErrCommandTimeout is returned when a command exceeds the configured timeout.
+// The accompanying CommandResult holds whatever output was captured.
+var ErrCommandTimeout = errors.New("command timed out")
+
 // Distro represents the detected Linux distribution.
 type Distro string
 
@@ -44,13 +62,27 @@ const (
 
 // Manager handles OS package updates.
 type Manager struct {
-	distro Distro
+	distro         Distro
+	commandTimeout time.Duration
+	cache          updatesCache
+
+	// background is set while RunRefresher keeps the package index fresh,
+	// so update checks skip refreshing it themselves
+	background atomic.Bool
+	refresh    struct {
+		mu     sync.Mutex
+		status RefreshStatus
+	}
 }
 
 // NewManager creates a new updates manager.
-func NewManager() *Manager {
+// Commands run by the manager are killed after commandTimeout (0 disables the limit).
+// Update checks are reused for cacheTTL (0 disables caching).
+func NewManager(commandTimeout, cacheTTL time.Duration) *Manager {
 	return &Manager{
-		distro: detectDistro(),
+		distro:         detectDistro(),
+		commandTimeout: commandTimeout,
+		cache:          updatesCache{ttl: cacheTTL},
 	}
 }
 
@@ -59,77 +91,237 @@ func (m *Manager) GetDistro() Distro {
 	return m.distro
 }
 
-// GetUpdates retrieves available package updates.
+// GetUpdates retrieves available package updates, marking held packages.
 func (m *Manager) GetUpdates(ctx context.Context) ([]PackageUpdate, error) {
-	log.Printf("[UPDATES] GetUpdates called, distro=%s", m.distro)
+	slog.Debug("GetUpdates called", "distro", m.distro)
+	var updates []PackageUpdate
+	var err error
 	switch m.distro {
 	case DistroDebian, DistroUbuntu:
-		return m.getAptUpdates(ctx)
+		updates, err = m.getAptUpdates(ctx)
 	case DistroRHEL, DistroCentOS, DistroFedora:
-		return m.getYumUpdates(ctx)
+		updates, err = m.getYumUpdates(ctx)
 	case DistroAlpine:
-		return m.getApkUpdates(ctx)
+		updates, err = m.getApkUpdates(ctx)
 	default:
-		log.Printf("[ERROR] Unsupported distribution: %s", m.distro)
+		slog.Error("Unsupported distribution", "distro", m.distro)
 		return nil, fmt.Errorf("unsupported distribution: %s", m.distro)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	held, err := m.GetHeldPackages(ctx)
+	if err != nil {
+		// Still useful without hold information
+		slog.Warn("Failed to list held packages", "error", err)
+		return updates, nil
+	}
+	for i := range updates {
+		updates[i].Held = slices.Contains(held, updates[i].Name)
+	}
+	return updates, nil
 }
 
-// ApplyUpdate installs a specific package update.
+// GetHeldPackages lists packages pinned against upgrades.
+func (m *Manager) GetHeldPackages(ctx context.Context) ([]string, error) {
+	switch m.distro {
+	case DistroDebian, DistroUbuntu:
+		result, err := m.executeCommand(ctx, "apt-mark", "showhold")
+		if err != nil {
+			return nil, err
+		}
+		if result.ExitCode != 0 {
+			return nil, fmt.Errorf("apt-mark showhold: %s", strings.TrimSpace(result.Stderr))
+		}
+		return strings.Fields(result.Stdout), nil
+	case DistroRHEL, DistroCentOS, DistroFedora:
+		result, err := m.executeCommand(ctx, "yum", "versionlock", "list")
+		if err != nil {
+			return nil, err
+		}
+		if result.ExitCode != 0 {
+			return nil, fmt.Errorf("yum versionlock list: %s", strings.TrimSpace(result.Stderr))
+		}
+		return parseVersionlockOutput(result.Stdout), nil
+	case DistroAlpine:
+		data, err := os.ReadFile("/etc/apk/world")
+		if err != nil {
+			return nil, err
+		}
+		return parseApkWorld(string(data)), nil
+	default:
+		return nil, fmt.Errorf("unsupported distribution: %s", m.distro)
+	}
+}
+
+// ApplyUpdate installs a specific package update. Held packages are refused
+// with ErrPackageHeld.
 func (m *Manager) ApplyUpdate(ctx context.Context, packageName string) (*CommandResult, error) {
-	log.Printf("[UPDATES] ApplyUpdate called, package=%s, distro=%s", packageName, m.distro)
+	if err := ValidatePackageName(packageName); err != nil {
+		return nil, err
+	}
+
+	held, err := m.GetHeldPackages(ctx)
+	if err != nil {
+		slog.Warn("Failed to list held packages", "error", err)
+	}
+	if slices.Contains(held, packageName) {
+		slog.Warn("Refusing to update held package", "package", packageName)
+		return nil, fmt.Errorf("%w: %s", ErrPackageHeld, packageName)
+	}
+
+	slog.Info("Applying package update", "package", packageName, "distro", m.distro)
+	defer m.InvalidateUpdates()
 	switch m.distro {
 	case DistroDebian, DistroUbuntu:
-		return executeCommand(ctx, "apt-get", "install", "-y", packageName)
+		return m.executeCommand(ctx, "apt-get", "install", "-y", packageName)
 	case DistroRHEL, DistroCentOS, DistroFedora:
-		return executeCommand(ctx, "yum", "update", "-y", packageName)
+		return m.executeCommand(ctx, "yum", "update", "-y", packageName)
 	case DistroAlpine:
-		return executeCommand(ctx, "apk", "add", "--upgrade", packageName)
+		return m.executeCommand(ctx, "apk", "add", "--upgrade", packageName)
 	default:
-		log.Printf("[ERROR] Unsupported distribution: %s", m.distro)
+		slog.Error("Unsupported distribution", "distro", m.distro)
 		return nil, fmt.Errorf("unsupported distribution: %s", m.distro)
 	}
 }
 
 // ApplyAllUpdates installs all available updates.
 func (m *Manager) ApplyAllUpdates(ctx context.Context) (*CommandResult, error) {
-	log.Printf("[UPDATES] ApplyAllUpdates called, distro=%s", m.distro)
+	slog.Info("Applying all package updates", "distro", m.distro)
+	defer m.InvalidateUpdates()
 	switch m.distro {
 	case DistroDebian, DistroUbuntu:
-		return executeCommand(ctx, "apt-get", "upgrade", "-y")
+		return m.executeCommand(ctx, "apt-get", "upgrade", "-y")
 	case DistroRHEL, DistroCentOS, DistroFedora:
-		return executeCommand(ctx, "yum", "update", "-y")
+		return m.executeCommand(ctx, "yum", "update", "-y")
 	case DistroAlpine:
-		return executeCommand(ctx, "apk", "upgrade")
+		return m.executeCommand(ctx, "apk", "upgrade")
 	default:
-		log.Printf("[ERROR] Unsupported distribution: %s", m.distro)
+		slog.Error("Unsupported distribution", "distro", m.distro)
 		return nil, fmt.Errorf("unsupported distribution: %s", m.distro)
 	}
 }
 
-// ExecuteCommand runs an arbitrary shell command.
-func ExecuteCommand(ctx context.Context, command string) (*CommandResult, error) {
-	return executeCommand(ctx, "sh", "-c", command)
+// SimulateAllUpdates reports what ApplyAllUpdates would change without
+// modifying the system. The simulation's raw output is returned alongside
+// the parsed package list.
+func (m *Manager) SimulateAllUpdates(ctx context.Context) ([]PackageUpdate, *CommandResult, error) {
+	slog.Info("Simulating all package updates", "distro", m.distro)
+	var result *CommandResult
+	var err error
+	var parse func(string) []PackageUpdate
+
+	switch m.distro {
+	case DistroDebian, DistroUbuntu:
+		result, err = m.executeCommand(ctx, "apt-get", "-s", "upgrade")
+		parse = parseAptSimulation
+	case DistroRHEL, DistroCentOS, DistroFedora:
+		// --assumeno answers "no" at the prompt, so yum exits 1 after listing the transaction
+		result, err = m.executeCommand(ctx, "yum", "update", "--assumeno")
+		parse = parseYumTransaction
+	case DistroAlpine:
+		result, err = m.executeCommand(ctx, "apk", "-s", "upgrade")
+		parse = parseApkSimulation
+	default:
+		slog.Error("Unsupported distribution", "distro", m.distro)
+		return nil, nil, fmt.Errorf("unsupported distribution: %s", m.distro)
+	}
+	if err != nil {
+		return nil, result, err
+	}
+	return parse(result.Stdout), result, nil
+}
+
+// ExecOptions sets the working directory and extra environment variables of
+// an arbitrary command. The zero value runs it in the agent's directory and
+// environment.
+type ExecOptions struct {
+	Dir string
+	Env map[string]string
+}
+
+// ErrInvalidEnv is returned for environment variable names that can't be set.
+var ErrInvalidEnv = errors.New("invalid environment variable name")
+
+// envNamePattern matches portable environment variable names.
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Validate checks the environment variable names and values.
+func (opts ExecOptions) Validate() error {
+	for name, value := range opts.Env {
+		if !envNamePattern.MatchString(name) {
+			return fmt.Errorf("%w: %q", ErrInvalidEnv, name)
+		}
+		if strings.ContainsRune(value, 0) {
+			return fmt.Errorf("%w: value of %s contains a NUL byte", ErrInvalidEnv, name)
+		}
+	}
+	return nil
+}
+
+// environ returns the agent's environment with opts.Env applied, or nil to
+// inherit it unchanged.
+func (opts ExecOptions) environ() []string {
+	if len(opts.Env) == 0 {
+		return nil
+	}
+	env := os.Environ()
+	for _, name := range slices.Sorted(maps.Keys(opts.Env)) {
+		env = append(env, name+"="+opts.Env[name])
+	}
+	return env
+}
+
+// ExecuteCommand runs an arbitrary shell command in the agent's environment
+// with opts applied. opts must already be validated.
+func (m *Manager) ExecuteCommand(ctx context.Context, command string, opts ExecOptions) (*CommandResult, error) {
+	return m.runCommand(ctx, opts.Dir, opts.environ(), "sh", "-c", command)
 }
 
 func (m *Manager) getAptUpdates(ctx context.Context) ([]PackageUpdate, error) {
-	// First, update package cache
-	_, err := executeCommand(ctx, "apt-get", "update", "-qq")
-	if err != nil {
-		return nil, fmt.Errorf("failed to update apt cache: %w", err)
+	// First, update package cache unless the background refresher does it
+	if !m.background.Load() {
+		_, err := m.executeCommand(ctx, "apt-get", "update", "-qq")
+		if err != nil {
+			return nil, fmt.Errorf("failed to update apt cache: %w", err)
+		}
 	}
 
 	// Get list of upgradable packages
-	result, err := executeCommand(ctx, "apt", "list", "--upgradable")
+	result, err := m.executeCommand(ctx, "apt", "list", "--upgradable")
 	if err != nil {
 		return nil, err
 	}
+	if result.ExitCode == 0 {
+		updates, dropped := parseAptOutput(result.Stdout)
+		if dropped == 0 {
+			return updates, nil
+		}
+		slog.Warn("apt list output was truncated, falling back to apt-get -s upgrade", "listed", len(updates), "dropped", dropped)
+	} else {
+		// apt is missing on some minimal images
+		slog.Warn("apt list failed, falling back to apt-get -s upgrade", "exitCode", result.ExitCode, "stderr", strings.TrimSpace(result.Stderr))
+	}
 
-	return parseAptOutput(result.Stdout), nil
+	// apt-get's simulation output is meant for scripts and stable across versions
+	result, err = m.executeCommand(ctx, "apt-get", "-s", "upgrade")
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("apt-get -s upgrade: %s", strings.TrimSpace(result.Stderr))
+	}
+	return parseAptSimulation(result.Stdout), nil
 }
 
 func (m *Manager) getYumUpdates(ctx context.Context) ([]PackageUpdate, error) {
-	result, err := executeCommand(ctx, "yum", "check-update", "-q")
+	args := []string{"check-update", "-q"}
+	if m.background.Load() {
+		// Use the metadata kept fresh by the background refresher
+		args = append(args, "--cacheonly")
+	}
+	result, err := m.executeCommand(ctx, "yum", args...)
 	// yum check-update returns exit code 100 if updates are available
 	if err != nil && result != nil && result.ExitCode != 100 && result.ExitCode != 0 {
 		return nil, err
@@ -139,53 +331,91 @@ func (m *Manager) getYumUpdates(ctx context.Context) ([]PackageUpdate, error) {
 }
 
 func (m *Manager) getApkUpdates(ctx context.Context) ([]PackageUpdate, error) {
-	log.Println("[UPDATES] Fetching Alpine apk updates")
-
-	// First update package cache
-	_, err := executeCommand(ctx, "apk", "update")
-	if err != nil {
-		log.Printf("[ERROR] Failed to update apk cache: %v", err)
-		return nil, fmt.Errorf("failed to update apk cache: %w", err)
+	slog.Debug("Fetching Alpine apk updates")
+
+	// First update package cache unless the background refresher does it
+	if !m.background.Load() {
+		_, err := m.executeCommand(ctx, "apk", "update")
+		if err != nil {
+			slog.Error("Failed to update apk cache", "error", err)
+			return nil, fmt.Errorf("failed to update apk cache: %w", err)
+		}
 	}
 
 	// Get list of upgradable packages
-	result, err := executeCommand(ctx, "apk", "list", "--upgradable")
+	result, err := m.executeCommand(ctx, "apk", "list", "--upgradable")
 	if err != nil {
-		log.Printf("[ERROR] Failed to list upgradable packages: %v", err)
+		slog.Error("Failed to list upgradable packages", "error", err)
 		return nil, err
 	}
 
-	log.Printf("[UPDATES] apk list --upgradable output: %s", result.Stdout)
+	slog.Debug("apk list --upgradable output", "output", result.Stdout)
 	return parseApkOutput(result.Stdout), nil
 }
 
+// aptTruncatedPattern matches the line that replaces the rest of a list cut
+// short, e.g. "... and 37 more."
+var aptTruncatedPattern = regexp.MustCompile(`^(?:\.\.\.|…)?\s*and (\d+) more\.?$`)
+
 // parseAptOutput parses the output of apt list --upgradable.
 // Format: package/repo version arch [upgradable from: current]
-func parseAptOutput(output string) []PackageUpdate {
-	var updates []PackageUpdate
+// Lines that can't be parsed are logged rather than silently dropped. If the
+// list was truncated, dropped is the number of packages left out.
+func parseAptOutput(output string) (updates []PackageUpdate, dropped int) {
 	scanner := bufio.NewScanner(strings.NewReader(output))
 
-	// Pattern: name/repo version arch [upgradable from: current_version]
-	re := regexp.MustCompile(`^([^/]+)/([^\s]+)\s+([^\s]+)\s+\S+\s+\[upgradable from:\s+([^\]]+)\]`)
-
 	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "Listing...") {
+		line := strings.TrimSpace(scanner.Text())
+		// Skip the header, notes such as "N: There is 1 additional version" and warnings
+		if line == "" || strings.HasPrefix(line, "Listing...") || strings.HasPrefix(line, "N: ") || strings.HasPrefix(line, "WARNING:") {
+			continue
+		}
+		if match := aptTruncatedPattern.FindStringSubmatch(line); match != nil {
+			n, _ := strconv.Atoi(match[1])
+			dropped += n
 			continue
 		}
 
-		matches := re.FindStringSubmatch(line)
-		if len(matches) >= 5 {
-			updates = append(updates, PackageUpdate{
-				Name:           matches[1],
-				Repository:     matches[2],
-				NewVersion:     matches[3],
-				CurrentVersion: matches[4],
-			})
+		update, ok := parseAptListLine(line)
+		if !ok {
+			slog.Warn("Skipping unrecognized apt list line", "line", line)
+			continue
 		}
+		updates = append(updates, update)
 	}
 
-	return updates
+	return updates, dropped
+}
+
+// parseAptListLine parses one apt list line. The repository may be missing
+// (e.g. "pkg/now") and the bracketed text is only assumed to end with the
+// installed version, so other wordings and locales still parse.
+func parseAptListLine(line string) (PackageUpdate, bool) {
+	head, bracket, _ := strings.Cut(line, " [")
+	fields := strings.Fields(head)
+	if len(fields) < 2 || !startsWithDigit(fields[1]) {
+		return PackageUpdate{}, false
+	}
+
+	name, repo, _ := strings.Cut(fields[0], "/")
+	if name == "" {
+		return PackageUpdate{}, false
+	}
+	if repo == "now" {
+		// Installed from a source no longer configured
+		repo = ""
+	}
+	update := PackageUpdate{Name: name, Repository: repo, NewVersion: fields[1]}
+
+	inner := strings.Fields(strings.TrimSuffix(strings.TrimSpace(bracket), "]"))
+	if len(inner) > 0 && startsWithDigit(inner[len(inner)-1]) {
+		update.CurrentVersion = inner[len(inner)-1]
+	}
+	return update, true
+}
+
+func startsWithDigit(s string) bool {
+	return s != "" && s[0] >= '0' && s[0] <= '9'
 }
 
 // parseYumOutput parses the output of yum check-update.
@@ -257,10 +487,121 @@ func parseApkOutput(output string) []PackageUpdate {
 		}
 	}
 
-	log.Printf("[UPDATES] Parsed %d Alpine packages for upgrade", len(updates))
+	slog.Debug("Parsed Alpine packages for upgrade", "count", len(updates))
 	return updates
 }
 
+// aptInstPattern matches apt-get -s "Inst" lines, e.g.
+// "Inst libssl3 [3.0.11-1] (3.0.13-1 Debian-Security:12/stable-security [amd64])".
+// The bracketed current version is absent for new packages.
+var aptInstPattern = regexp.MustCompile(`^Inst (\S+) (?:\[([^\]]+)\] )?\((\S+) ([^\[)]*)`)
+
+// parseAptSimulation parses the packages apt-get -s upgrade would install.
+func parseAptSimulation(output string) []PackageUpdate {
+	var updates []PackageUpdate
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		matches := aptInstPattern.FindStringSubmatch(scanner.Text())
+		if matches == nil {
+			continue
+		}
+		updates = append(updates, PackageUpdate{
+			Name:           matches[1],
+			CurrentVersion: matches[2],
+			NewVersion:     matches[3],
+			Repository:     strings.TrimSpace(matches[4]),
+		})
+	}
+	return updates
+}
+
+// parseYumTransaction parses the Upgrading/Updating/Installing sections of
+// a yum transaction summary. Rows are "name arch version repository size".
+func parseYumTransaction(output string) []PackageUpdate {
+	var updates []PackageUpdate
+	inSection := false
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			inSection = false
+			continue
+		case !strings.HasPrefix(line, " "):
+			// Section headers such as "Upgrading:" or "Installing dependencies:"
+			inSection = strings.HasPrefix(trimmed, "Upgrading") ||
+				strings.HasPrefix(trimmed, "Updating") ||
+				strings.HasPrefix(trimmed, "Installing")
+			continue
+		}
+		if !inSection {
+			continue
+		}
+
+		fields := strings.Fields(trimmed)
+		if len(fields) < 5 {
+			continue
+		}
+		updates = append(updates, PackageUpdate{
+			Name:       fields[0],
+			NewVersion: fields[2],
+			Repository: fields[3],
+		})
+	}
+	return updates
+}
+
+// apkUpgradePattern matches apk -s lines like "(1/3) Upgrading busybox (1.36.1-r5 -> 1.36.1-r6)".
+var apkUpgradePattern = regexp.MustCompile(`\) (?:Upgrading|Installing) (\S+) \((?:(\S+) -> )?(\S+)\)`)
+
+// parseApkSimulation parses the packages apk -s upgrade would change.
+func parseApkSimulation(output string) []PackageUpdate {
+	var updates []PackageUpdate
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		matches := apkUpgradePattern.FindStringSubmatch(scanner.Text())
+		if matches == nil {
+			continue
+		}
+		updates = append(updates, PackageUpdate{
+			Name:           matches[1],
+			CurrentVersion: matches[2],
+			NewVersion:     matches[3],
+		})
+	}
+	return updates
+}
+
+// versionlockPattern matches a yum versionlock entry, "[epoch:]name-[epoch:]version-release[.arch]",
+// after any trailing ".*" is removed.
+var versionlockPattern = regexp.MustCompile(`^(?:\d+:)?(.+?)-(?:\d+:)?\d[^-]*-[^-]+$`)
+
+// parseVersionlockOutput extracts package names from yum versionlock list.
+func parseVersionlockOutput(output string) []string {
+	var names []string
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSuffix(strings.TrimSpace(scanner.Text()), ".*")
+		if matches := versionlockPattern.FindStringSubmatch(line); matches != nil {
+			names = append(names, matches[1])
+		}
+	}
+	return names
+}
+
+// parseApkWorld returns the packages in /etc/apk/world that are pinned to a
+// version, e.g. "busybox=1.36.1-r5" or "nginx<1.25".
+func parseApkWorld(world string) []string {
+	var names []string
+	for _, entry := range strings.Fields(world) {
+		if i := strings.IndexAny(entry, "=<>~"); i > 0 {
+			names = append(names, entry[:i])
+		}
+	}
+	return names
+}
+
 // splitPackageVersion splits "package-version" into name and version.
 // Alpine packages use format like: busybox-1.35.0-r3
 func splitPackageVersion(pkgVersion string) (name, version string) {
@@ -276,26 +617,60 @@ func splitPackageVersion(pkgVersion string) (name, version string) {
 	return pkgVersion, ""
 }
 
-func executeCommand(ctx context.Context, name string, args ...string) (*CommandResult, error) {
+// packageEnv is added to the environment of package manager commands so they
+// never prompt and print untranslated output for the parsers.
+var packageEnv = []string{"DEBIAN_FRONTEND=noninteractive", "LC_ALL=C"}
+
+// executeCommand runs a package manager command with packageEnv.
+func (m *Manager) executeCommand(ctx context.Context, name string, args ...string) (*CommandResult, error) {
+	return m.runCommand(ctx, "", append(os.Environ(), packageEnv...), name, args...)
+}
+
+// runCommand runs a command in dir, killing it if it exceeds the manager's
+// timeout. An empty dir and nil env inherit the agent's.
+// On timeout the partial result is returned together with ErrCommandTimeout.
+func (m *Manager) runCommand(ctx context.Context, dir string, env []string, name string, args ...string) (*CommandResult, error) {
+	if m.commandTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.commandTimeout)
+		defer cancel()
+	}
+
 	start := time.Now()
 
 	cmd := exec.CommandContext(ctx, name, args...)
+	// Don't wait forever on pipes held open by orphaned grandchildren after a kill
+	cmd.WaitDelay = 2 * time.Second
+	cmd.Dir = dir
+	cmd.Env = env
+
+	// Capture stderr even on success, where warnings and deprecation notices go
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
 
-	stdout, err := cmd.Output()
+	err := cmd.Run()
 	duration := time.Since(start).Milliseconds()
 
 	result := &CommandResult{
-		Stdout:   string(stdout),
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
 		Duration: duration,
 	}
 
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		slog.Error("Command timed out", "command", name, "timeout", m.commandTimeout)
+		result.ExitCode = -1
+		result.TimedOut = true
+		return result, fmt.Errorf("%w after %v", ErrCommandTimeout, m.commandTimeout)
+	}
+
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
-			result.Stderr = string(exitErr.Stderr)
 			result.ExitCode = exitErr.ExitCode()
 		} else {
 			result.ExitCode = -1
-			result.Stderr = err.Error()
+			result.Stderr += err.Error()
 		}
 	}
 
@@ -307,52 +682,52 @@ func detectDistro() Distro {
 	data, err := os.ReadFile("/etc/os-release")
 	if err == nil {
 		content := strings.ToLower(string(data))
-		log.Printf("[UPDATES] /etc/os-release content: %s", strings.ReplaceAll(content, "\n", " | "))
+		slog.Debug("Read /etc/os-release", "content", strings.ReplaceAll(content, "\n", " | "))
 
 		switch {
 		case strings.Contains(content, "alpine"):
-			log.Println("[UPDATES] Detected Alpine Linux")
+			slog.Info("Detected distribution", "distro", "Alpine Linux")
 			return DistroAlpine
 		case strings.Contains(content, "ubuntu"):
-			log.Println("[UPDATES] Detected Ubuntu")
+			slog.Info("Detected distribution", "distro", "Ubuntu")
 			return DistroUbuntu
 		case strings.Contains(content, "debian"):
-			log.Println("[UPDATES] Detected Debian")
+			slog.Info("Detected distribution", "distro", "Debian")
 			return DistroDebian
 		case strings.Contains(content, "centos"):
-			log.Println("[UPDATES] Detected CentOS")
+			slog.Info("Detected distribution", "distro", "CentOS")
 			return DistroCentOS
 		case strings.Contains(content, "rhel"), strings.Contains(content, "red hat"):
-			log.Println("[UPDATES] Detected RHEL")
+			slog.Info("Detected distribution", "distro", "RHEL")
 			return DistroRHEL
 		case strings.Contains(content, "fedora"):
-			log.Println("[UPDATES] Detected Fedora")
+			slog.Info("Detected distribution", "distro", "Fedora")
 			return DistroFedora
 		}
 	} else {
-		log.Printf("[UPDATES] Could not read /etc/os-release: %v", err)
+		slog.Debug("Could not read /etc/os-release", "error", err)
 	}
 
 	// Fallback: detect by checking which package manager binary exists
-	log.Println("[UPDATES] Falling back to package manager binary detection")
+	slog.Debug("Falling back to package manager binary detection")
 
 	if _, err := exec.LookPath("apk"); err == nil {
-		log.Println("[UPDATES] Found apk - assuming Alpine")
+		slog.Info("Detected distribution from package manager", "binary", "apk", "distro", "Alpine")
 		return DistroAlpine
 	}
 	if _, err := exec.LookPath("apt-get"); err == nil {
-		log.Println("[UPDATES] Found apt-get - assuming Debian/Ubuntu")
+		slog.Info("Detected distribution from package manager", "binary", "apt-get", "distro", "Debian/Ubuntu")
 		return DistroDebian
 	}
 	if _, err := exec.LookPath("yum"); err == nil {
-		log.Println("[UPDATES] Found yum - assuming RHEL/CentOS")
+		slog.Info("Detected distribution from package manager", "binary", "yum", "distro", "RHEL/CentOS")
 		return DistroRHEL
 	}
 	if _, err := exec.LookPath("dnf"); err == nil {
-		log.Println("[UPDATES] Found dnf - assuming Fedora")
+		slog.Info("Detected distribution from package manager", "binary", "dnf", "distro", "Fedora")
 		return DistroFedora
 	}
 
-	log.Println("[UPDATES] Could not detect distribution")
+	slog.Warn("Could not detect distribution")
 	return DistroUnknown
 }
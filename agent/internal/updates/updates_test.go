@@ -0,0 +1,149 @@
+package updates
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestExecuteCommandTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh and sleep")
+	}
+	m := NewManager(200*time.Millisecond, 0)
+
+	start := time.Now()
+	result, err := m.ExecuteCommand(context.Background(), "echo started; sleep 10", ExecOptions{})
+	if !errors.Is(err, ErrCommandTimeout) {
+		t.Fatalf("got error %v, want ErrCommandTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("command ran for %v despite a 200ms timeout", elapsed)
+	}
+	if result == nil || !result.TimedOut {
+		t.Fatalf("got result %+v, want TimedOut", result)
+	}
+	if result.Stdout != "started\n" {
+		t.Errorf("Stdout = %q, want the output captured before the timeout", result.Stdout)
+	}
+}
+
+func TestStreamCommandTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh and sleep")
+	}
+	m := NewManager(200*time.Millisecond, 0)
+
+	out := make(chan OutputLine, 10)
+	result, err := m.StreamCommand(context.Background(), "sleep 10", ExecOptions{}, nil, out)
+	for range out {
+	}
+	if !errors.Is(err, ErrCommandTimeout) {
+		t.Fatalf("got error %v, want ErrCommandTimeout", err)
+	}
+	if result == nil || !result.TimedOut {
+		t.Fatalf("got result %+v, want TimedOut", result)
+	}
+}
+
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseAptOutput(t *testing.T) {
+	for _, tc := range []struct {
+		fixture string
+		want    []PackageUpdate
+	}{
+		{"apt-list-ubuntu-20.04.txt", []PackageUpdate{
+			{Name: "libssl1.1", Repository: "focal-updates,focal-security", NewVersion: "1.1.1f-1ubuntu2.23", CurrentVersion: "1.1.1f-1ubuntu2.22"},
+			{Name: "openssl", Repository: "focal-updates,focal-security", NewVersion: "1.1.1f-1ubuntu2.23", CurrentVersion: "1.1.1f-1ubuntu2.22"},
+			{Name: "tzdata", Repository: "focal-updates", NewVersion: "2024a-0ubuntu0.20.04.1", CurrentVersion: "2023c-0ubuntu0.20.04.2"},
+		}},
+		{"apt-list-ubuntu-22.04.txt", []PackageUpdate{
+			{Name: "libc-bin", Repository: "jammy-updates", NewVersion: "2.35-0ubuntu3.8", CurrentVersion: "2.35-0ubuntu3.6"},
+			{Name: "libc6", Repository: "jammy-updates", NewVersion: "2.35-0ubuntu3.8", CurrentVersion: "2.35-0ubuntu3.6"},
+			{Name: "linux-firmware", Repository: "jammy-updates,jammy-updates", NewVersion: "20220329.git681281e4-0ubuntu3.36", CurrentVersion: "20220329.git681281e4-0ubuntu3.31"},
+		}},
+		// Trailing "N:" note about additional versions
+		{"apt-list-ubuntu-24.04.txt", []PackageUpdate{
+			{Name: "curl", Repository: "noble-updates,noble-security", NewVersion: "8.5.0-2ubuntu10.4", CurrentVersion: "8.5.0-2ubuntu10.1"},
+			{Name: "libcurl4t64", Repository: "noble-updates,noble-security", NewVersion: "8.5.0-2ubuntu10.4", CurrentVersion: "8.5.0-2ubuntu10.1"},
+		}},
+		// CLI stability warning and blank lines before the header
+		{"apt-list-debian-11.txt", []PackageUpdate{
+			{Name: "base-files", Repository: "oldstable", NewVersion: "11.1+deb11u10", CurrentVersion: "11.1+deb11u9"},
+			{Name: "libgnutls30", Repository: "oldstable-security", NewVersion: "3.7.1-5+deb11u5", CurrentVersion: "3.7.1-5+deb11u4"},
+		}},
+		// Multiarch package listed once per architecture, plural "N:" note
+		{"apt-list-debian-12.txt", []PackageUpdate{
+			{Name: "libc6", Repository: "stable", NewVersion: "2.36-9+deb12u7", CurrentVersion: "2.36-9+deb12u4"},
+			{Name: "libc6", Repository: "stable", NewVersion: "2.36-9+deb12u7", CurrentVersion: "2.36-9+deb12u4"},
+			{Name: "sudo", Repository: "stable-security", NewVersion: "1.9.13p3-1+deb12u1", CurrentVersion: "1.9.13p3-1"},
+		}},
+		// Header without "Done" and a package whose source is gone
+		{"apt-list-debian-13.txt", []PackageUpdate{
+			{Name: "bash", Repository: "testing", NewVersion: "5.2.37-1", CurrentVersion: "5.2.32-1+b2"},
+			{Name: "legacy-tool", NewVersion: "2.1-1", CurrentVersion: "2.0-3"},
+		}},
+	} {
+		t.Run(tc.fixture, func(t *testing.T) {
+			got, dropped := parseAptOutput(readFixture(t, tc.fixture))
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v\nwant %+v", got, tc.want)
+			}
+			if dropped != 0 {
+				t.Errorf("dropped = %d, want 0", dropped)
+			}
+		})
+	}
+}
+
+func TestParseAptOutputTruncated(t *testing.T) {
+	got, dropped := parseAptOutput(readFixture(t, "apt-list-ubuntu-22.04-truncated.txt"))
+	want := []PackageUpdate{
+		{Name: "libc-bin", Repository: "jammy-updates", NewVersion: "2.35-0ubuntu3.8", CurrentVersion: "2.35-0ubuntu3.6"},
+		{Name: "libc6", Repository: "jammy-updates", NewVersion: "2.35-0ubuntu3.8", CurrentVersion: "2.35-0ubuntu3.6"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+	if dropped != 41 {
+		t.Errorf("dropped = %d, want 41", dropped)
+	}
+}
+
+func TestParseAptSimulation(t *testing.T) {
+	for _, tc := range []struct {
+		fixture string
+		want    []PackageUpdate
+	}{
+		{"apt-get-s-upgrade-debian-12.txt", []PackageUpdate{
+			{Name: "libssl3", CurrentVersion: "3.0.11-1~deb12u2", NewVersion: "3.0.15-1~deb12u1", Repository: "Debian-Security:12/stable-security"},
+			{Name: "openssl", CurrentVersion: "3.0.11-1~deb12u2", NewVersion: "3.0.15-1~deb12u1", Repository: "Debian-Security:12/stable-security"},
+		}},
+		// Several origins per package and a newly installed kernel
+		{"apt-get-s-upgrade-ubuntu-22.04.txt", []PackageUpdate{
+			{Name: "libc6", CurrentVersion: "2.35-0ubuntu3.6", NewVersion: "2.35-0ubuntu3.8", Repository: "Ubuntu:22.04/jammy-updates, Ubuntu:22.04/jammy-security"},
+			{Name: "libc-bin", CurrentVersion: "2.35-0ubuntu3.6", NewVersion: "2.35-0ubuntu3.8", Repository: "Ubuntu:22.04/jammy-updates, Ubuntu:22.04/jammy-security"},
+			{Name: "linux-image-5.15.0-125-generic", NewVersion: "5.15.0-125.135", Repository: "Ubuntu:22.04/jammy-updates"},
+		}},
+	} {
+		t.Run(tc.fixture, func(t *testing.T) {
+			got := parseAptSimulation(readFixture(t, tc.fixture))
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v\nwant %+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -125,6 +125,8 @@ export interface DockerStatus {
   installed: boolean;
   containers: DockerContainer[];
   images: DockerImage[];
+  totalContainers: number;
+  totalImages: number;
 }
 
 /**